@@ -0,0 +1,70 @@
+package birdlog
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// prefixes of lines written by bird to a log file, syslog and the journal
+	prefixRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)? <\w+> (.*)$`),
+		regexp.MustCompile(`^(?:<\d+>)?.*?\bbird6?(?:\[\d+\])?: (.*)$`),
+	}
+	protocolMessageRegex = regexp.MustCompile(`^([A-Za-z_][\w.]*): (.*)$`)
+	stateRegex           = regexp.MustCompile(`^State changed to (\w+)`)
+	// errors of the kernel syncer are logged without protocol name
+	netlinkRegex = regexp.MustCompile(`^(?:Netlink: |KRT: |Kernel dropped some netlink messages)`)
+
+	events = []struct {
+		name  string
+		regex *regexp.Regexp
+	}{
+		{"hold_timer_expired", regexp.MustCompile(`(?i)hold timer expired`)},
+		{"connection_lost", regexp.MustCompile(`^Connection lost`)},
+		{"connection_refused", regexp.MustCompile(`(?i)connection refused`)},
+		{"limit_exceeded", regexp.MustCompile(`(?i)limit.*(exceeded|reached)`)},
+		{"graceful_restart", regexp.MustCompile(`(?i)graceful restart`)},
+		{"notification_received", regexp.MustCompile(`^Received: `)},
+		{"rpki_cache_error", regexp.MustCompile(`(?i)(cache.*\b(error|fail\w*|unreachable|expired)|error (report|pdu)|lost connection)`)},
+		{"netlink_error", regexp.MustCompile(`(?i)netlink`)},
+		{"error", regexp.MustCompile(`^Error: `)},
+	}
+)
+
+// ParseLine returns the protocol and the event of a log line written by bird. Channel
+// names (e.g. bgp1.ipv4) are mapped to their protocol, events without protocol (netlink
+// errors) have an empty name. Lines not matching a known event are ignored
+func ParseLine(line string) (name, event string, ok bool) {
+	msg := strings.TrimSpace(line)
+	for _, re := range prefixRegexes {
+		if match := re.FindStringSubmatch(msg); match != nil {
+			msg = match[1]
+			break
+		}
+	}
+
+	if netlinkRegex.MatchString(msg) {
+		return "", "netlink_error", true
+	}
+
+	match := protocolMessageRegex.FindStringSubmatch(msg)
+	if match == nil {
+		return "", "", false
+	}
+
+	name, _, _ = strings.Cut(match[1], ".")
+	msg = match[2]
+
+	if state := stateRegex.FindStringSubmatch(msg); state != nil {
+		return name, "state_" + strings.ToLower(state[1]), true
+	}
+
+	for _, e := range events {
+		if e.regex.MatchString(msg) {
+			return name, e.name, true
+		}
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,114 @@
+package birdlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		name  string
+		event string
+		ok    bool
+	}{
+		{
+			line:  "2024-01-01 12:00:00.123 <INFO> bgp1: State changed to up",
+			name:  "bgp1",
+			event: "state_up",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00 <RMT> bgp_transit1: Received: Hold timer expired",
+			name:  "bgp_transit1",
+			event: "hold_timer_expired",
+			ok:    true,
+		},
+		{
+			line:  "Jan  1 12:00:00 router1 bird[1234]: bgp2: Error: Hold timer expired",
+			name:  "bgp2",
+			event: "hold_timer_expired",
+			ok:    true,
+		},
+		{
+			line:  "<30>Jan  1 12:00:00 bird: bgp2: Received: Cease (Administrative shutdown)",
+			name:  "bgp2",
+			event: "notification_received",
+			ok:    true,
+		},
+		{
+			line:  "bgp3.ipv6: State changed to down",
+			name:  "bgp3",
+			event: "state_down",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00.123 <WARN> bgp3.ipv4: Import limit (1000) exceeded",
+			name:  "bgp3",
+			event: "limit_exceeded",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00.123 <RMT> bgp1: Connection lost (Connection reset by peer)",
+			name:  "bgp1",
+			event: "connection_lost",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00.123 <ERR> Netlink: Network is unreachable",
+			name:  "",
+			event: "netlink_error",
+			ok:    true,
+		},
+		{
+			line:  "Jan  1 12:00:00 router1 bird: Kernel dropped some netlink messages, will resync on next scan.",
+			name:  "",
+			event: "netlink_error",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00.123 <WARN> kernel1: Netlink: File exists",
+			name:  "kernel1",
+			event: "netlink_error",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00.123 <WARN> rpki1: Cache server 192.0.2.10 is unreachable",
+			name:  "rpki1",
+			event: "rpki_cache_error",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00.123 <INFO> rpki1: Lost connection: Connection reset by peer",
+			name:  "rpki1",
+			event: "rpki_cache_error",
+			ok:    true,
+		},
+		{
+			line:  "2024-01-01 12:00:00.123 <WARN> rpki1: Received error report (No data available)",
+			name:  "rpki1",
+			event: "rpki_cache_error",
+			ok:    true,
+		},
+		{
+			line: "2024-01-01 12:00:00.123 <INFO> rpki1: Cache server 192.0.2.10 initialized",
+		},
+		{
+			line: "2024-01-01 12:00:00.123 <INFO> Reconfiguring",
+		},
+		{
+			line: "2024-01-01 12:00:00.123 <INFO> bgp1: Reconfigured",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			name, event, ok := ParseLine(test.line)
+
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.event, event)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package birdlog
+
+import (
+	"sync"
+)
+
+// UnknownProtocol is the name events are counted for if they do not belong to a protocol found
+// on the last scrape (e.g. messages of the kernel syncer or of removed protocols)
+const UnknownProtocol = "unknown"
+
+var (
+	protocolsMu sync.RWMutex
+	protocols   = make(map[string]*ProtocolNames)
+)
+
+// ProtocolNames contains the names of the protocols of a bird instance found on the last scrape
+type ProtocolNames struct {
+	mu    sync.RWMutex
+	names map[string]struct{}
+}
+
+// ProtocolNamesFor returns the protocol names of the instance
+func ProtocolNamesFor(instance string) *ProtocolNames {
+	protocolsMu.Lock()
+	defer protocolsMu.Unlock()
+
+	p, found := protocols[instance]
+	if !found {
+		p = &ProtocolNames{}
+		protocols[instance] = p
+	}
+
+	return p
+}
+
+// Set replaces the protocol names by the ones of the current scrape
+func (p *ProtocolNames) Set(names []string) {
+	m := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		m[n] = struct{}{}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.names = m
+}
+
+func (p *ProtocolNames) contains(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	_, found := p.names[name]
+	return found
+}
+
+// knownProtocol checks if the protocol was found on the last scrape of any instance
+func knownProtocol(name string) bool {
+	protocolsMu.RLock()
+	defer protocolsMu.RUnlock()
+
+	for _, p := range protocols {
+		if p.contains(name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,167 @@
+package birdlog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// pollInterval is the interval a tailed file is checked for new lines
+var pollInterval = time.Second
+
+// TailFile calls handle for every line appended to the file until the context is canceled.
+// Rotated and truncated files are reopened
+func TailFile(ctx context.Context, path string, handle func(line string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	// only lines written after the start are processed
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	partial := ""
+
+	for {
+		line, err := r.ReadString('\n')
+		offset += int64(len(line))
+
+		if err == nil {
+			handle(strings.TrimRight(partial+line, "\r\n"))
+			partial = ""
+			continue
+		}
+
+		if err != io.EOF {
+			return err
+		}
+		partial += line
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+
+		reopen, err := rotated(f, path, offset)
+		if err != nil || !reopen {
+			continue
+		}
+
+		n, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		// lines written to the old file since the last read are processed before switching files
+		drain(r, partial, handle)
+
+		f.Close()
+		f = n
+		r.Reset(f)
+		offset = 0
+		partial = ""
+	}
+}
+
+// drain processes the lines left in the reader of a rotated file. A last line without line break
+// is processed as well, as nothing is appended to a rotated file anymore
+func drain(r *bufio.Reader, partial string, handle func(line string)) {
+	for {
+		line, err := r.ReadString('\n')
+		partial += line
+
+		if err != nil {
+			break
+		}
+
+		handle(strings.TrimRight(partial, "\r\n"))
+		partial = ""
+	}
+
+	if partial != "" {
+		handle(strings.TrimRight(partial, "\r\n"))
+	}
+}
+
+// rotated checks whether the file at path was replaced or truncated
+func rotated(f *os.File, path string, offset int64) (bool, error) {
+	current, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return !os.SameFile(current, info) || info.Size() < offset, nil
+}
+
+// ListenSocket calls handle for every message received on a unix datagram socket (e.g. forwarded
+// by syslog) until the context is canceled
+func ListenSocket(ctx context.Context, path string, handle func(line string)) error {
+	err := removeSocket(path)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFromUnix(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if line != "" {
+				handle(line)
+			}
+		}
+	}
+}
+
+// removeSocket removes the socket left by a previous run. Other files are never removed
+func removeSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("could not listen on %s: file exists and is not a socket", path)
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,155 @@
+package birdlog
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lines struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *lines) add(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+}
+
+func (l *lines) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func TestTailFile(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "bird.log")
+	require.NoError(t, os.WriteFile(path, []byte("old line\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	l := &lines{}
+	go func() {
+		TailFile(ctx, path, l.add)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	f.WriteString("line 1\nline")
+	f.Sync()
+	time.Sleep(50 * time.Millisecond)
+	f.WriteString(" 2\n")
+	f.Close()
+
+	assert.Eventually(t, func() bool { return len(l.get()) == 2 }, time.Second, 10*time.Millisecond)
+
+	// rotation
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("line 3\n"), 0644))
+
+	assert.Eventually(t, func() bool { return len(l.get()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"line 1", "line 2", "line 3"}, l.get())
+}
+
+func TestTailFileRotationDrainsOldFile(t *testing.T) {
+	pollInterval = 200 * time.Millisecond
+	defer func() { pollInterval = time.Second }()
+
+	path := filepath.Join(t.TempDir(), "bird.log")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	l := &lines{}
+	go func() {
+		TailFile(ctx, path, l.add)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+
+	f.WriteString("line 1\n")
+	assert.Eventually(t, func() bool { return len(l.get()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// lines are written to the old file and it is rotated while the tailer waits for new lines
+	f.WriteString("line 2\nline 3")
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("line 4\n"), 0644))
+
+	assert.Eventually(t, func() bool { return len(l.get()) == 4 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"line 1", "line 2", "line 3", "line 4"}, l.get())
+}
+
+func TestListenSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &lines{}
+	go ListenSocket(ctx, path, l.add)
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	conn, err := net.Dial("unixgram", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<30>Jan  1 12:00:00 bird: bgp1: State changed to up"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return len(l.get()) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestListenSocketReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.sock")
+
+	stale, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	stale.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, ListenSocket(ctx, path, func(string) {}))
+}
+
+func TestListenSocketKeepsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.log")
+	require.NoError(t, os.WriteFile(path, []byte("line 1\n"), 0644))
+
+	err := ListenSocket(context.Background(), path, func(string) {})
+	assert.Error(t, err)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\n", string(b))
+}
@@ -0,0 +1,54 @@
+package birdlog
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var logEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "bird_log_events_total",
+	Help: "Number of events of a protocol found in the bird log",
+}, []string{"name", "event"})
+
+// Collectors returns the collectors of the events found in the bird log
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{logEvents}
+}
+
+// Watch counts the events in the log file and the messages received on the socket until the
+// context is canceled. Empty sources are ignored
+func Watch(ctx context.Context, file, socket string) {
+	if file != "" {
+		go func() {
+			err := TailFile(ctx, file, handleLine)
+			if err != nil {
+				log.Errorf("could not tail bird log %s: %v", file, err)
+			}
+		}()
+	}
+
+	if socket != "" {
+		go func() {
+			err := ListenSocket(ctx, socket, handleLine)
+			if err != nil {
+				log.Errorf("could not receive bird log on %s: %v", socket, err)
+			}
+		}()
+	}
+}
+
+func handleLine(line string) {
+	name, event, ok := ParseLine(line)
+	if !ok {
+		return
+	}
+
+	// names are limited to the known protocols to bound the number of series
+	if !knownProtocol(name) {
+		name = UnknownProtocol
+	}
+
+	logEvents.WithLabelValues(name, event).Inc()
+}
@@ -0,0 +1,28 @@
+package birdlog
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleLine(t *testing.T) {
+	logEvents.Reset()
+	ProtocolNamesFor("").Set([]string{"bgp1"})
+
+	handleLine("2024-01-01 12:00:00.123 <INFO> bgp1: State changed to down")
+	handleLine("2024-01-01 12:00:00.123 <INFO> bgp2: State changed to down")
+	handleLine("Jan  1 12:00:00 router1 sshd[42]: Invalid user admin: Error: Connection closed")
+	handleLine("2024-01-01 12:00:00.123 <ERR> Netlink: Network is unreachable")
+
+	assert.Equal(t, 3, testutil.CollectAndCount(logEvents))
+	assert.Equal(t, float64(1), testutil.ToFloat64(logEvents.WithLabelValues("bgp1", "state_down")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(logEvents.WithLabelValues(UnknownProtocol, "state_down")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(logEvents.WithLabelValues(UnknownProtocol, "netlink_error")))
+
+	// protocols removed from the config are not known anymore
+	ProtocolNamesFor("").Set([]string{"bgp2"})
+	handleLine("2024-01-01 12:00:00.123 <INFO> bgp1: State changed to up")
+	assert.Equal(t, float64(1), testutil.ToFloat64(logEvents.WithLabelValues(UnknownProtocol, "state_up")))
+}
@@ -2,13 +2,14 @@ package client
 
 import (
 	"fmt"
+	"io"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/czerwonk/bird_exporter/parser"
 	"github.com/czerwonk/bird_exporter/protocol"
-	birdsocket "github.com/czerwonk/bird_socket"
 )
 
 // BirdClient communicates with the bird socket to retrieve information
@@ -23,6 +24,7 @@ type BirdClientOptions struct {
 	Bird6Enabled bool
 	BirdSocket   string
 	Bird6Socket  string
+	Timeout      time.Duration
 }
 
 // GetProtocols retrieves protocol information and statistics from bird
@@ -45,8 +47,7 @@ func (c *BirdClient) GetProtocols() ([]*protocol.Protocol, error) {
 
 // GetOSPFAreas retrieves OSPF specific information from bird
 func (c *BirdClient) GetOSPFAreas(protocol *protocol.Protocol) ([]*protocol.OSPFArea, error) {
-	sock := c.socketFor(protocol.IPVersion)
-	b, err := birdsocket.Query(sock, fmt.Sprintf("show ospf %s", protocol.Name))
+	b, err := c.query(protocol.IPVersion, fmt.Sprintf("show ospf %s", protocol.Name))
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +57,7 @@ func (c *BirdClient) GetOSPFAreas(protocol *protocol.Protocol) ([]*protocol.OSPF
 
 // GetBFDSessions retrieves BFD specific information from bird
 func (c *BirdClient) GetBFDSessions(protocol *protocol.Protocol) ([]*protocol.BFDSession, error) {
-	sock := c.socketFor(protocol.IPVersion)
-	b, err := birdsocket.Query(sock, fmt.Sprintf("show bfd sessions %s", protocol.Name))
+	b, err := c.query(protocol.IPVersion, fmt.Sprintf("show bfd sessions %s", protocol.Name))
 	if err != nil {
 		return nil, err
 	}
@@ -67,33 +67,35 @@ func (c *BirdClient) GetBFDSessions(protocol *protocol.Protocol) ([]*protocol.BF
 
 // GetPrefixStats retrieves prefix length statistics from routing table
 func (c *BirdClient) GetPrefixStats(proto *protocol.Protocol) (*protocol.PrefixStats, error) {
-	sock := c.socketFor(proto.IPVersion)
-	
 	// Try multiple commands to get comprehensive route information
 	tableName := "master4"
 	if proto.IPVersion == "6" {
 		tableName = "master6"
 	}
 	
-	commands := []string{
-		fmt.Sprintf("show route all protocol %s", proto.Name),  // ALL routes from protocol (including filtered)
-		fmt.Sprintf("show route protocol %s all", proto.Name),  // More detailed output  
-		fmt.Sprintf("show route protocol %s", proto.Name),      // Standard output (only selected routes)
-		fmt.Sprintf("show route table %s protocol %s all", tableName, proto.Name), // Table-specific with all
-		fmt.Sprintf("show route where source = RTS_%s", getRouteSource(proto.Proto)), // By route source
+	commands := []struct {
+		cmd string
+		// output contains the route attributes
+		all bool
+	}{
+		{fmt.Sprintf("show route all protocol %s", proto.Name), true},  // ALL routes from protocol (including filtered)
+		{fmt.Sprintf("show route protocol %s all", proto.Name), true},  // More detailed output  
+		{fmt.Sprintf("show route protocol %s", proto.Name), false},      // Standard output (only selected routes)
+		{fmt.Sprintf("show route table %s protocol %s all", tableName, proto.Name), true}, // Table-specific with all
+		{fmt.Sprintf("show route where source = RTS_%s", getRouteSource(proto.Proto)), false}, // By route source
 	}
 	
 	var stats *protocol.PrefixStats
 	var lastErr error
 	
-	for _, cmd := range commands {
-		b, err := birdsocket.Query(sock, cmd)
+	for _, x := range commands {
+		s, err := c.prefixStats(proto, x.cmd, x.all)
 		if err != nil {
 			lastErr = err
 			continue
 		}
 		
-		stats = parser.ParsePrefixStats(proto.Name, proto.IPVersion, b)
+		stats = s
 		
 		// If we got a reasonable number of routes, use this result
 		totalRoutes := int64(0)
@@ -115,17 +117,68 @@ func (c *BirdClient) GetPrefixStats(proto *protocol.Protocol) (*protocol.PrefixS
 	return nil, lastErr
 }
 
+// prefixStats runs a route query and parses its output. Output containing the route attributes is
+// streamed to also collect the AS path lengths without keeping the routes in memory
+func (c *BirdClient) prefixStats(proto *protocol.Protocol, qry string, all bool) (*protocol.PrefixStats, error) {
+	if !all {
+		b, err := c.query(proto.IPVersion, qry)
+		if err != nil {
+			return nil, err
+		}
+
+		return parser.ParsePrefixStats(proto.Name, proto.IPVersion, b), nil
+	}
+
+	var stats *protocol.PrefixStats
+	err := c.queryStream(proto.IPVersion, qry, func(r io.Reader) error {
+		var err error
+		stats, err = parser.ScanPrefixStats(proto.Name, proto.IPVersion, r)
+		return err
+	})
+
+	return stats, err
+}
+
+// GetRouteCount retrieves the number of routes of the protocol matching the filter condition
+func (c *BirdClient) GetRouteCount(proto *protocol.Protocol, condition string) (int64, error) {
+	qry := fmt.Sprintf("show route protocol %s where %s count", proto.Name, condition)
+	if proto.Table != "" {
+		qry = fmt.Sprintf("show route table %s protocol %s where %s count", proto.Table, proto.Name, condition)
+	}
+
+	b, err := c.query(proto.IPVersion, qry)
+	if err != nil {
+		return 0, err
+	}
+
+	return parseRouteCount(b), nil
+}
+
+// GetRoutes retrieves the routes of the protocol matching the filter condition (all routes if empty) and calls fn for every route
+func (c *BirdClient) GetRoutes(proto *protocol.Protocol, condition string, fn func(*protocol.Route)) error {
+	qry := fmt.Sprintf("show route protocol %s", proto.Name)
+	if proto.Table != "" {
+		qry = fmt.Sprintf("show route table %s protocol %s", proto.Table, proto.Name)
+	}
+
+	if condition != "" {
+		qry += " where " + condition
+	}
+
+	return c.queryStream(proto.IPVersion, qry, func(r io.Reader) error {
+		return parser.ScanRoutes(r, fn)
+	})
+}
+
 // GetAllPrefixStats retrieves prefix length statistics for all routes in a table
 func (c *BirdClient) GetAllPrefixStats(ipVersion string) (*protocol.PrefixStats, error) {
-	sock := c.socketFor(ipVersion)
-	
 	tableName := "master4"
 	if ipVersion == "6" {
 		tableName = "master6"
 	}
 	
 	// Use count-based approach for large datasets since each route generates ~4 lines
-	countStats, err := c.getCountBasedPrefixStats(sock, tableName, ipVersion)
+	countStats, err := c.getCountBasedPrefixStats(tableName, ipVersion)
 	if err == nil && countStats != nil {
 		totalRoutes := int64(0)
 		for _, count := range countStats.PrefixLengthCounts {
@@ -137,7 +190,7 @@ func (c *BirdClient) GetAllPrefixStats(ipVersion string) (*protocol.PrefixStats,
 	}
 	
 	// Fallback to sampling approach for very large datasets
-	sampleStats, err := c.getSampledPrefixStats(sock, tableName, ipVersion)
+	sampleStats, err := c.getSampledPrefixStats(tableName, ipVersion)
 	if err == nil && sampleStats != nil {
 		return sampleStats, nil
 	}
@@ -146,7 +199,7 @@ func (c *BirdClient) GetAllPrefixStats(ipVersion string) (*protocol.PrefixStats,
 }
 
 // getCountBasedPrefixStats uses BIRD's count functionality to efficiently get prefix statistics
-func (c *BirdClient) getCountBasedPrefixStats(sock, tableName, ipVersion string) (*protocol.PrefixStats, error) {
+func (c *BirdClient) getCountBasedPrefixStats(tableName, ipVersion string) (*protocol.PrefixStats, error) {
 	stats := protocol.NewPrefixStats(ipVersion, "all_routes")
 	
 	// Define prefix length ranges to query - optimized based on real BGP table data
@@ -168,7 +221,7 @@ func (c *BirdClient) getCountBasedPrefixStats(sock, tableName, ipVersion string)
 			cmd = fmt.Sprintf("show route table %s where net ~ [0.0.0.0/0{%d,%d}] primary count", tableName, prefixLen, prefixLen)
 		}
 		
-		b, err := birdsocket.Query(sock, cmd)
+		b, err := c.query(ipVersion, cmd)
 		if err != nil {
 			continue // Skip failed queries
 		}
@@ -183,7 +236,7 @@ func (c *BirdClient) getCountBasedPrefixStats(sock, tableName, ipVersion string)
 }
 
 // getSampledPrefixStats uses sampling to estimate prefix distribution for very large datasets
-func (c *BirdClient) getSampledPrefixStats(sock, tableName, ipVersion string) (*protocol.PrefixStats, error) {
+func (c *BirdClient) getSampledPrefixStats(tableName, ipVersion string) (*protocol.PrefixStats, error) {
 	stats := protocol.NewPrefixStats(ipVersion, "all_routes")
 	
 	// Try to get a sample of routes - BIRD doesn't support limit keyword
@@ -194,11 +247,11 @@ func (c *BirdClient) getSampledPrefixStats(sock, tableName, ipVersion string) (*
 		cmd = fmt.Sprintf("show route table %s", tableName)
 	}
 	
-	b, err := birdsocket.Query(sock, cmd)
+	b, err := c.query(ipVersion, cmd)
 	if err != nil {
 		// Try simpler command
 		simpleCmd := "show route"
-		b, err = birdsocket.Query(sock, simpleCmd)
+		b, err = c.query(ipVersion, simpleCmd)
 		if err != nil {
 			return nil, err
 		}
@@ -208,7 +261,7 @@ func (c *BirdClient) getSampledPrefixStats(sock, tableName, ipVersion string) (*
 	
 	// Get total route count for scaling
 	totalCmd := fmt.Sprintf("show route table %s count", tableName)
-	totalBytes, err := birdsocket.Query(sock, totalCmd)
+	totalBytes, err := c.query(ipVersion, totalCmd)
 	if err != nil {
 		return sampleStats, nil // Return sample without scaling
 	}
@@ -239,8 +292,9 @@ func parseRouteCount(data []byte) int64 {
 	
 	// Look for BIRD v2 format: "1007-197991 of 440662 routes for 220785 networks in table master6"
 	// The format is: error_code-count of total_routes routes for networks in table
-	// We want the number after the dash (filtered routes matching our criteria)
-	countRegexV2 := regexp.MustCompile(`^(\d+)-(\d+)\s+of\s+\d+\s+routes\s+for\s+\d+\s+networks\s+in\s+table`)
+	// We want the number after the code (filtered routes matching our criteria). The last line of
+	// the reply uses a space instead of the dash ("0014 2 of 2 routes for 2 networks in table master4")
+	countRegexV2 := regexp.MustCompile(`^(\d+)[-\s](\d+)\s+of\s+\d+\s+routes\s+for\s+\d+\s+networks\s+in\s+table`)
 	
 	// Also support simple format: "42 routes" or "0 routes"
 	countRegex := regexp.MustCompile(`^(\d+)\s+routes?`)
@@ -290,8 +344,7 @@ func (c *BirdClient) protocolsFromBird(ipVersions []string) ([]*protocol.Protoco
 	protocols := make([]*protocol.Protocol, 0)
 
 	for _, ipVersion := range ipVersions {
-		sock := c.socketFor(ipVersion)
-		s, err := c.protocolsFromSocket(sock, ipVersion)
+		s, err := c.protocolsFromSocket(ipVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -302,8 +355,8 @@ func (c *BirdClient) protocolsFromBird(ipVersions []string) ([]*protocol.Protoco
 	return protocols, nil
 }
 
-func (c *BirdClient) protocolsFromSocket(socketPath string, ipVersion string) ([]*protocol.Protocol, error) {
-	b, err := birdsocket.Query(socketPath, "show protocols all")
+func (c *BirdClient) protocolsFromSocket(ipVersion string) ([]*protocol.Protocol, error) {
+	b, err := c.query(ipVersion, "show protocols all")
 	if err != nil {
 		return nil, err
 	}
@@ -318,3 +371,49 @@ func (c *BirdClient) socketFor(ipVersion string) string {
 
 	return c.Options.BirdSocket
 }
+
+func (c *BirdClient) query(ipVersion, qry string) ([]byte, error) {
+	t, err := NewTransport(c.socketFor(ipVersion), c.Options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	b, err := t.Query(qry)
+	observeQuery(qry, start, int64(len(b)), err)
+
+	return b, err
+}
+
+// queryStream passes the reply to fn while it is read from bird
+func (c *BirdClient) queryStream(ipVersion, qry string, fn func(io.Reader) error) error {
+	t, err := NewTransport(c.socketFor(ipVersion), c.Options.Timeout)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var n int64
+	err = t.Stream(qry, func(r io.Reader) error {
+		cr := &countingReader{r: r}
+		defer func() { n = cr.n }()
+
+		return fn(cr)
+	})
+	observeQuery(qry, start, n, err)
+
+	return err
+}
+
+// countingReader counts the bytes read from the reply
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+
+	return n, err
+}
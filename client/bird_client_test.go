@@ -0,0 +1,37 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRouteCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected int64
+	}{
+		{
+			name:     "continued reply",
+			data:     "1007-197991 of 440662 routes for 220785 networks in table master6\n0000 \n",
+			expected: 197991,
+		},
+		{
+			name:     "last line of reply",
+			data:     "0014 2 of 5 routes for 2 networks in table master4\n",
+			expected: 2,
+		},
+		{
+			name:     "no routes",
+			data:     "0000 \n",
+			expected: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, parseRouteCount([]byte(test.data)))
+		})
+	}
+}
@@ -19,4 +19,10 @@ type Client interface {
 
 	// GetAllPrefixStats retrieves prefix length statistics for all routes in a table
 	GetAllPrefixStats(ipVersion string) (*protocol.PrefixStats, error)
+
+	// GetRouteCount retrieves the number of routes of the protocol matching the filter condition
+	GetRouteCount(proto *protocol.Protocol, condition string) (int64, error)
+
+	// GetRoutes retrieves the routes of the protocol matching the filter condition (all routes if empty) and calls fn for every route
+	GetRoutes(proto *protocol.Protocol, condition string, fn func(*protocol.Route)) error
 }
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bird_exporter_bird_query_duration_seconds",
+		Help:    "Duration of queries sent to bird by command",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"command"})
+	queryBytesRead = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bird_exporter_bird_query_read_bytes_total",
+		Help: "Number of bytes read from bird by command",
+	}, []string{"command"})
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bird_exporter_bird_query_errors_total",
+		Help: "Number of failed queries sent to bird by command",
+	}, []string{"command"})
+
+	// commands used as label, arguments (e.g. protocol names) are stripped to limit cardinality
+	knownCommands = []string{
+		"show protocols all",
+		"show ospf",
+		"show bfd sessions",
+	}
+)
+
+// Collectors returns the collectors instrumenting the communication with bird
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{queryDuration, queryBytesRead, queryErrors}
+}
+
+func observeQuery(qry string, start time.Time, bytesRead int64, err error) {
+	cmd := commandLabel(qry)
+	queryDuration.WithLabelValues(cmd).Observe(time.Since(start).Seconds())
+	queryBytesRead.WithLabelValues(cmd).Add(float64(bytesRead))
+
+	if err != nil {
+		queryErrors.WithLabelValues(cmd).Inc()
+	}
+}
+
+func commandLabel(qry string) string {
+	qry = strings.TrimSpace(qry)
+
+	if strings.HasPrefix(qry, "show route") {
+		if strings.HasSuffix(qry, " count") {
+			return "show route count"
+		}
+
+		return "show route"
+	}
+
+	for _, c := range knownCommands {
+		if strings.HasPrefix(qry, c) {
+			return c
+		}
+	}
+
+	return "other"
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandLabel(t *testing.T) {
+	tests := map[string]string{
+		"show protocols all":                   "show protocols all",
+		"show ospf ospf1":                      "show ospf",
+		"show bfd sessions bfd1":               "show bfd sessions",
+		"show route all protocol bgp1":         "show route",
+		"show route table master4 count":       "show route count",
+		"show route where net ~ [::/0{48,48}]": "show route",
+		"show status":                          "other",
+	}
+
+	for qry, expected := range tests {
+		assert.Equal(t, expected, commandLabel(qry), qry)
+	}
+}
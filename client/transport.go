@@ -0,0 +1,272 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	birdsocket "github.com/czerwonk/bird_socket"
+)
+
+const bufferSize = 4096
+
+var (
+	// sshCommand is the binary used to connect to remote hosts via SSH
+	sshCommand = "ssh"
+
+	replyCompletedRegex = regexp.MustCompile(`(?m)^([089]\d{3})`)
+
+	// birdcRegex matches plain paths of the birdc binary (no shell meta characters)
+	birdcRegex = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+)
+
+// Transport sends queries to a bird instance and returns the raw reply
+type Transport interface {
+	// Query sends a command to bird and waits for the reply
+	Query(qry string) ([]byte, error)
+
+	// Stream sends a command to bird and passes the reply to fn while it is read,
+	// so large replies (e.g. routes) are not kept in memory
+	Stream(qry string, fn func(io.Reader) error) error
+}
+
+// NewTransport creates a transport for the given socket specification.
+// Supported formats are plain paths or unix:///path/to/socket (local Unix socket),
+// tcp://host:port (e.g. a socat proxy to the bird socket) and
+// ssh://user@host[:port]/path/to/socket (executes birdc -r -v on the remote host).
+// Queries of remote transports are aborted after the timeout
+func NewTransport(socket string, timeout time.Duration) (Transport, error) {
+	if !strings.Contains(socket, "://") {
+		return &unixTransport{path: socket}, nil
+	}
+
+	u, err := url.Parse(socket)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bird socket %q: %w", socket, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid bird socket %q: missing path", socket)
+		}
+
+		return &unixTransport{path: u.Path}, nil
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid bird socket %q: missing host", socket)
+		}
+
+		return &tcpTransport{address: u.Host, timeout: timeout}, nil
+	case "ssh":
+		return newSSHTransport(u, timeout)
+	}
+
+	return nil, fmt.Errorf("invalid bird socket %q: unsupported scheme %s", socket, u.Scheme)
+}
+
+type unixTransport struct {
+	path string
+}
+
+func (t *unixTransport) Query(qry string) ([]byte, error) {
+	return birdsocket.Query(t.path, qry)
+}
+
+func (t *unixTransport) Stream(qry string, fn func(io.Reader) error) error {
+	conn, err := net.Dial("unix", t.path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return streamReply(conn, qry, fn)
+}
+
+type tcpTransport struct {
+	address string
+	timeout time.Duration
+}
+
+func (t *tcpTransport) Query(qry string) ([]byte, error) {
+	return readAll(t, qry)
+}
+
+func (t *tcpTransport) Stream(qry string, fn func(io.Reader) error) error {
+	conn, err := net.DialTimeout("tcp", t.address, t.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(t.timeout))
+	if err != nil {
+		return err
+	}
+
+	return streamReply(conn, qry, fn)
+}
+
+// streamReply skips the greeting of bird, sends the query and passes the reply to fn
+func streamReply(conn net.Conn, qry string, fn func(io.Reader) error) error {
+	r := bufio.NewReaderSize(conn, bufferSize)
+
+	// bird greets every new connection with a "0001 BIRD x.y.z ready." line
+	_, err := io.Copy(io.Discard, &replyReader{r: r})
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write([]byte(strings.Trim(qry, "\n") + "\n"))
+	if err != nil {
+		return err
+	}
+
+	return fn(&replyReader{r: r})
+}
+
+// replyReader reads a single reply of the bird socket. The reply ends with the first line
+// starting with a completion or error code
+type replyReader struct {
+	r    *bufio.Reader
+	line []byte
+	done bool
+}
+
+func (rr *replyReader) Read(p []byte) (int, error) {
+	if len(rr.line) == 0 {
+		if rr.done {
+			return 0, io.EOF
+		}
+
+		line, err := rr.r.ReadBytes('\n')
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		rr.line = line
+		rr.done = replyCompletedRegex.Match(line)
+	}
+
+	n := copy(p, rr.line)
+	rr.line = rr.line[n:]
+
+	return n, nil
+}
+
+// readAll returns the complete reply of a query
+func readAll(t Transport, qry string) ([]byte, error) {
+	var b []byte
+	err := t.Stream(qry, func(r io.Reader) error {
+		var err error
+		b, err = io.ReadAll(r)
+		return err
+	})
+
+	return b, err
+}
+
+type sshTransport struct {
+	destination string
+	port        string
+	socketPath  string
+	birdc       string
+	timeout     time.Duration
+}
+
+func newSSHTransport(u *url.URL, timeout time.Duration) (*sshTransport, error) {
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid bird socket %q: missing host", u.Redacted())
+	}
+
+	if u.Path == "" {
+		return nil, fmt.Errorf("invalid bird socket %q: missing socket path", u.Redacted())
+	}
+
+	dest := u.Hostname()
+	if u.User != nil {
+		dest = u.User.Username() + "@" + dest
+	}
+
+	birdc := u.Query().Get("birdc")
+	if birdc == "" {
+		birdc = "birdc"
+	}
+
+	if !birdcRegex.MatchString(birdc) {
+		return nil, fmt.Errorf("invalid bird socket %q: birdc must be a plain path", u.Redacted())
+	}
+
+	return &sshTransport{
+		destination: dest,
+		port:        u.Port(),
+		socketPath:  u.Path,
+		birdc:       birdc,
+		timeout:     timeout,
+	}, nil
+}
+
+func (t *sshTransport) Query(qry string) ([]byte, error) {
+	return readAll(t, qry)
+}
+
+func (t *sshTransport) Stream(qry string, fn func(io.Reader) error) error {
+	args := []string{"-o", "BatchMode=yes"}
+	if t.port != "" {
+		args = append(args, "-p", t.port)
+	}
+
+	// -v keeps the reply codes, so the reply has the same format as replies of the socket
+	remoteCmd := fmt.Sprintf("%s -r -v -s %s %s", shellQuote(t.birdc), shellQuote(t.socketPath), shellQuote(strings.TrimSpace(qry)))
+	args = append(args, t.destination, remoteCmd)
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, sshCommand, args...)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("could not query bird on %s: %w", t.destination, err)
+	}
+
+	fnErr := fn(stdout)
+
+	// the rest of the reply is discarded, so ssh is not blocked writing it
+	io.Copy(io.Discard, stdout)
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return fmt.Errorf("could not query bird on %s: %w", t.destination, ctx.Err())
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not query bird on %s: %w (%s)", t.destination, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return fnErr
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
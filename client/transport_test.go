@@ -0,0 +1,265 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		socket   string
+		expected Transport
+		wantErr  bool
+	}{
+		{
+			socket:   "/var/run/bird.ctl",
+			expected: &unixTransport{path: "/var/run/bird.ctl"},
+		},
+		{
+			socket:   "unix:///run/bird/bird.ctl",
+			expected: &unixTransport{path: "/run/bird/bird.ctl"},
+		},
+		{
+			socket:   "tcp://192.0.2.1:8000",
+			expected: &tcpTransport{address: "192.0.2.1:8000", timeout: time.Second},
+		},
+		{
+			socket: "ssh://exporter@router1/var/run/bird.ctl",
+			expected: &sshTransport{
+				destination: "exporter@router1",
+				socketPath:  "/var/run/bird.ctl",
+				birdc:       "birdc",
+				timeout:     time.Second,
+			},
+		},
+		{
+			socket: "ssh://router1:2222/var/run/bird6.ctl?birdc=birdc6",
+			expected: &sshTransport{
+				destination: "router1",
+				port:        "2222",
+				socketPath:  "/var/run/bird6.ctl",
+				birdc:       "birdc6",
+				timeout:     time.Second,
+			},
+		},
+		{socket: "ssh://router1", wantErr: true},
+		{socket: "ssh://router1/var/run/bird.ctl?birdc=birdc%3Bid", wantErr: true},
+		{socket: "ssh://router1/var/run/bird.ctl?birdc=$(id)", wantErr: true},
+		{socket: "ssh://router1/var/run/bird.ctl?birdc=/usr/sbin/birdc%20-l", wantErr: true},
+		{socket: "tcp://", wantErr: true},
+		{socket: "http://router1/", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.socket, func(t *testing.T) {
+			tr, err := NewTransport(test.socket, time.Second)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, tr)
+		})
+	}
+}
+
+func TestTCPTransportQuery(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("0001 BIRD 2.0.8 ready.\n"))
+
+		cmd, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || cmd != "show protocols all\n" {
+			conn.Write([]byte("9001 syntax error\n"))
+			return
+		}
+
+		conn.Write([]byte("2002-Name       Proto      Table      State  Since         Info\n" +
+			"1002-bgp1       BGP        ---        up     2018-01-01 01:00:00  Established\n" +
+			"0000 \n"))
+	}()
+
+	tr, err := NewTransport("tcp://"+l.Addr().String(), time.Second)
+	require.NoError(t, err)
+
+	b, err := tr.Query("show protocols all")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "1002-bgp1")
+	assert.Contains(t, string(b), "0000 \n")
+}
+
+func TestUnixTransportStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bird.ctl")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("0001 BIRD 2.0.8 ready.\n"))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("1007-Table master4:\n" +
+			" 192.0.2.0/24         unicast [bgp1 2024-01-01] * (100) [AS65001i]\n" +
+			"0000 \n"))
+
+		// the connection is kept open, the end of the reply is detected by the completion code
+		<-done
+	}()
+
+	tr, err := NewTransport(path, time.Second)
+	require.NoError(t, err)
+
+	lines := []string{}
+	err = tr.Stream("show route all", func(r io.Reader) error {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+
+		return scanner.Err()
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1007-Table master4:", " 192.0.2.0/24         unicast [bgp1 2024-01-01] * (100) [AS65001i]", "0000 "}, lines)
+}
+
+func TestTCPTransportStreamIncompleteReply(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("0001 BIRD 2.0.8 ready.\n"))
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("1007-Table master4:\n"))
+	}()
+
+	tr, err := NewTransport("tcp://"+l.Addr().String(), time.Second)
+	require.NoError(t, err)
+
+	_, err = tr.Query("show route all")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestTCPTransportTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// greeting is sent, but the query is never answered
+		conn.Write([]byte("0001 BIRD 2.0.8 ready.\n"))
+		bufio.NewReader(conn).ReadString('\n')
+		time.Sleep(time.Second)
+	}()
+
+	tr, err := NewTransport("tcp://"+l.Addr().String(), 50*time.Millisecond)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = tr.Query("show protocols all")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestSSHTransportTimeout(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ssh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 5\n"), 0o755)
+	require.NoError(t, err)
+
+	orig := sshCommand
+	sshCommand = script
+	defer func() { sshCommand = orig }()
+
+	tr, err := NewTransport("ssh://exporter@router1/var/run/bird.ctl", 50*time.Millisecond)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = tr.Query("show protocols all")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestSSHTransportQuery(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ssh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\nfor arg in \"$@\"; do echo \"$arg\"; done\n"), 0o755)
+	require.NoError(t, err)
+
+	orig := sshCommand
+	sshCommand = script
+	defer func() { sshCommand = orig }()
+
+	tr, err := NewTransport("ssh://exporter@router1:2222/var/run/bird.ctl", time.Second)
+	require.NoError(t, err)
+
+	b, err := tr.Query("show ospf ospf1")
+	require.NoError(t, err)
+
+	expected := "-o\nBatchMode=yes\n-p\n2222\nexporter@router1\n'birdc' -r -v -s '/var/run/bird.ctl' 'show ospf ospf1'\n"
+	assert.Equal(t, expected, string(b))
+}
+
+func TestSSHTransportRouteCount(t *testing.T) {
+	// birdc prints the reply codes only in verbose mode
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ssh")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+case "$*" in
+*" -v "*) echo "0014 2 of 5 routes for 2 networks in table master4" ;;
+*) echo "2 of 5 routes for 2 networks in table master4" ;;
+esac
+`), 0o755)
+	require.NoError(t, err)
+
+	orig := sshCommand
+	sshCommand = script
+	defer func() { sshCommand = orig }()
+
+	c := &BirdClient{Options: &BirdClientOptions{BirdV2: true, BirdSocket: "ssh://exporter@router1/var/run/bird.ctl", Timeout: time.Second}}
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "", 3600)
+
+	count, err := c.GetRouteCount(p, "(65535, 6) ~ bgp_community")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeCollector is a collector consisting of named collectors which can be selected on scrape
+type scrapeCollector interface {
+	prometheus.Collector
+	collectorNames() []string
+	collect(ch chan<- prometheus.Metric, include func(name string) bool)
+}
+
+// collectorFilter contains the collectors selected by collect[] parameters (all collectors if empty)
+type collectorFilter map[string]bool
+
+// collectorFilterFromRequest parses the collect[] parameters of the request. Only collectors
+// used by at least one of the given collectors can be selected
+func collectorFilterFromRequest(r *http.Request, collectors ...scrapeCollector) (collectorFilter, error) {
+	available := []string{}
+	for _, c := range collectors {
+		available = append(available, c.collectorNames()...)
+	}
+
+	f := make(collectorFilter)
+	for _, name := range r.URL.Query()["collect[]"] {
+		if !slices.Contains(available, name) {
+			return nil, fmt.Errorf("unknown or disabled collector %q", name)
+		}
+
+		f[name] = true
+	}
+
+	return f, nil
+}
+
+func (f collectorFilter) include(name string) bool {
+	return len(f) == 0 || f[name]
+}
+
+// apply restricts the collector to the selected collectors
+func (f collectorFilter) apply(c scrapeCollector) prometheus.Collector {
+	if len(f) == 0 {
+		return c
+	}
+
+	return &filteredCollector{scrapeCollector: c, filter: f}
+}
+
+// filteredCollector runs only the collectors selected by the filter
+type filteredCollector struct {
+	scrapeCollector
+	filter collectorFilter
+}
+
+func (c *filteredCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch, c.filter.include)
+}
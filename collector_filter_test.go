@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectorFilterFromRequest(t *testing.T) {
+	p1 := &poller{names: []string{"protocols", "ospf"}}
+	p2 := &poller{names: []string{"protocols", "prefix_size"}}
+
+	tests := []struct {
+		name     string
+		url      string
+		expected collectorFilter
+		wantErr  bool
+	}{
+		{
+			name:     "no parameters",
+			url:      "/metrics",
+			expected: collectorFilter{},
+		},
+		{
+			name:     "collectors of different instances",
+			url:      "/metrics?collect[]=ospf&collect[]=prefix_size",
+			expected: collectorFilter{"ospf": true, "prefix_size": true},
+		},
+		{
+			name:    "disabled collector",
+			url:     "/metrics?collect[]=bfd",
+			wantErr: true,
+		},
+		{
+			name:    "unknown collector",
+			url:     "/metrics?collect[]=isis",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := collectorFilterFromRequest(httptest.NewRequest("GET", test.url, nil), p1, p2)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, f)
+		})
+	}
+}
+
+func TestCollectorFilterApply(t *testing.T) {
+	p := &poller{
+		names: []string{"protocols", "prefix_size"},
+		snapshots: map[string]*snapshot{
+			"protocols": {
+				timestamp: time.Now(),
+				metrics:   []prometheus.Metric{prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, 1, "bgp1")},
+			},
+			"prefix_size": {
+				timestamp: time.Now(),
+				metrics:   []prometheus.Metric{prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, 2, "bgp1")},
+			},
+		},
+	}
+
+	assert.Same(t, p, collectorFilter{}.apply(p))
+
+	ch := make(chan prometheus.Metric, 10)
+	collectorFilter{"prefix_size": true}.apply(p).Collect(ch)
+	close(ch)
+
+	values := []float64{}
+	for m := range ch {
+		values = append(values, metricValue(t, m))
+	}
+
+	if assert.Len(t, values, 2, "metric and snapshot age") {
+		assert.Equal(t, float64(2), values[0])
+	}
+}
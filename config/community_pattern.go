@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var communityPatternRegex = regexp.MustCompile(`^\(\s*(\d+|\*|\d+\.\.\d+)\s*,\s*(\d+|\*|\d+\.\.\d+)\s*(?:,\s*(\d+|\*|\d+\.\.\d+)\s*)?\)$`)
+
+// CommunityPattern defines a pattern of a standard (e.g. "(65000, 100)") or a large community
+// (e.g. "(65000, 1, *)"). Parts of the community can be wildcards (*) or ranges (100..200)
+type CommunityPattern struct {
+	Name      string `yaml:"name"`
+	Community string `yaml:"community"`
+}
+
+// Condition returns the bird filter condition matching routes tagged with the community
+func (c *CommunityPattern) Condition() string {
+	match := communityPatternRegex.FindStringSubmatch(strings.TrimSpace(c.Community))
+	if match == nil {
+		return ""
+	}
+
+	if match[3] != "" {
+		return fmt.Sprintf("bgp_large_community ~ [(%s, %s, %s)]", match[1], match[2], match[3])
+	}
+
+	return fmt.Sprintf("bgp_community ~ [(%s, %s)]", match[1], match[2])
+}
+
+func (c *CommunityPattern) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+
+	if c.Condition() == "" {
+		return fmt.Errorf("invalid community %q of %s", c.Community, c.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommunityPatternCondition(t *testing.T) {
+	tests := []struct {
+		community string
+		expected  string
+	}{
+		{
+			community: "(65535, 666)",
+			expected:  "bgp_community ~ [(65535, 666)]",
+		},
+		{
+			community: "(65000,*)",
+			expected:  "bgp_community ~ [(65000, *)]",
+		},
+		{
+			community: "(65000, 1, 100..200)",
+			expected:  "bgp_large_community ~ [(65000, 1, 100..200)]",
+		},
+		{
+			community: "65535:666",
+			expected:  "",
+		},
+		{
+			community: "(65000, 1); reload",
+			expected:  "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.community, func(t *testing.T) {
+			c := &CommunityPattern{Name: "test", Community: test.community}
+			assert.Equal(t, test.expected, c.Condition())
+		})
+	}
+}
@@ -0,0 +1,639 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/exporter-toolkit/web"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultModuleName is the name of the module used when no module is specified
+const DefaultModuleName = "default"
+
+var protocolNames = map[string]protocol.Proto{
+	"bgp":    protocol.BGP,
+	"ospf":   protocol.OSPF,
+	"kernel": protocol.Kernel,
+	"static": protocol.Static,
+	"direct": protocol.Direct,
+	"babel":  protocol.Babel,
+	"rpki":   protocol.RPKI,
+	"bfd":    protocol.BFD,
+}
+
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// protocolLabels are the labels of protocol metrics in the new format (state is only added to the up metric)
+var protocolLabels = []string{"name", "proto", "ip_version", "import_filter", "export_filter", "state"}
+
+// CollectorNames contains the names of all collectors
+var CollectorNames = []string{"protocols", "ospf", "bfd", "prefix_size", "table_prefix_size", "bgp_capabilities", "bgp_graceful_restart", "bgp_as_routes", "bgp_communities", "blackhole", "next_hops"}
+
+// Config is the representation of the configuration file
+type Config struct {
+	Bird          Bird `yaml:"bird"`
+	Metrics       `yaml:",inline"`
+	Web           Web                `yaml:"web"`
+	Polling       Polling            `yaml:"polling"`
+	StateTracking StateTracking      `yaml:"state_tracking"`
+	Logs          Logs               `yaml:"logs"`
+	Modules       map[string]*Module `yaml:"modules"`
+	Instances     []*Instance        `yaml:"instances"`
+	// ProbeTargets are the targets the probe endpoint may query. If empty only local sockets and
+	// tcp:// targets are allowed
+	ProbeTargets []string `yaml:"probe_targets"`
+}
+
+// Bird defines how to connect to the bird instance queried by the metrics endpoint
+type Bird struct {
+	Socket  string `yaml:"socket"`
+	Socket6 string `yaml:"socket6"`
+	V2      bool   `yaml:"v2"`
+	IPv4    bool   `yaml:"ipv4"`
+	IPv6    bool   `yaml:"ipv6"`
+	// Timeout is the maximum duration of a query to a remote bird instance (TCP or SSH)
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Web defines the settings of the web server (changes require a restart)
+type Web struct {
+	ListenAddresses []string `yaml:"listen_addresses"`
+	SystemdSocket   bool     `yaml:"systemd_socket"`
+	ConfigFile      string   `yaml:"config_file"`
+	TelemetryPath   string   `yaml:"telemetry_path"`
+	ProbePath       string   `yaml:"probe_path"`
+}
+
+// Polling defines the settings of the background polling mode. When enabled collectors are run
+// on their own interval and the metrics endpoint serves the last snapshot of each collector
+type Polling struct {
+	Enabled   bool                     `yaml:"enabled"`
+	Interval  time.Duration            `yaml:"interval"`
+	Intervals map[string]time.Duration `yaml:"intervals"`
+}
+
+// StateTracking defines the settings of the tracking of protocol state changes across scrapes.
+// If a file is set the states are persisted to track changes across restarts
+type StateTracking struct {
+	Enabled bool   `yaml:"enabled"`
+	File    string `yaml:"file"`
+}
+
+// Logs defines the sources of the bird log events are counted from. File is tailed (following
+// rotations), on Socket a unix datagram socket is created syslog can forward messages to
+type Logs struct {
+	File   string `yaml:"file"`
+	Socket string `yaml:"socket"`
+}
+
+// Metrics defines which metrics are exported and how they are labeled. With DescriptionLabelsConsistent
+// or DescriptionLabelsKeys all protocols get the same description labels (missing labels are empty)
+type Metrics struct {
+	Protocols                   []string       `yaml:"protocols"`
+	NewFormat                   bool           `yaml:"new_format"`
+	FormatV3                    bool           `yaml:"format_v3"`
+	InfoMetric                  bool           `yaml:"info_metric"`
+	DescriptionLabels           bool           `yaml:"description_labels"`
+	DescriptionLabelsRegex      string         `yaml:"description_labels_regex"`
+	DescriptionLabelsConsistent bool           `yaml:"description_labels_consistent"`
+	DescriptionLabelsKeys       []string       `yaml:"description_labels_keys"`
+	NameLabelsRegexes           []string       `yaml:"name_labels_regexes"`
+	Collectors                  Collectors     `yaml:"collectors"`
+	PrefixStats                 PrefixStats    `yaml:"prefix_stats"`
+	Concurrency                 int            `yaml:"concurrency"`
+	ProtocolFilter              ProtocolFilter `yaml:"protocol_filter"`
+	PeerMetadata                PeerMetadata   `yaml:"peer_metadata"`
+	BGP                         BGP            `yaml:"bgp"`
+	Blackhole                   Blackhole      `yaml:"blackhole"`
+}
+
+// Collectors enables or disables protocol specific collectors
+type Collectors struct {
+	OSPFAreas          bool `yaml:"ospf_areas"`
+	BFDSessions        bool `yaml:"bfd_sessions"`
+	BGPCapabilities    bool `yaml:"bgp_capabilities"`
+	BGPGracefulRestart bool `yaml:"bgp_graceful_restart"`
+	NextHops           bool `yaml:"next_hops"`
+}
+
+// BGP defines the settings of the BGP specific collectors
+type BGP struct {
+	// StaleRoutesFilter is the condition used to count the stale routes of a session during graceful restart
+	StaleRoutesFilter string `yaml:"stale_routes_filter"`
+	// WatchASNs are the AS numbers the routes of every session are counted for by origin and first AS
+	WatchASNs []int64 `yaml:"watch_asns"`
+	// Communities are the (large) community patterns routes are counted for
+	Communities []CommunityPattern `yaml:"communities"`
+}
+
+// Blackhole defines the settings of the collector of blackhole routes (tagged with the BLACKHOLE community
+// or with blackhole destination). With PrefixInfoLimit > 0 an info series is exported per prefix (at most
+// PrefixInfoLimit per protocol)
+type Blackhole struct {
+	Enabled         bool `yaml:"enabled"`
+	PrefixInfoLimit int  `yaml:"prefix_info_limit"`
+}
+
+// PrefixStats defines the settings of the prefix size statistics
+type PrefixStats struct {
+	Enabled   bool     `yaml:"enabled"`
+	Protocols []string `yaml:"protocols"`
+	Table     bool     `yaml:"table"`
+}
+
+// PeerMetadata defines a file (YAML or CSV) with metadata of peers added as labels to the
+// protocol metrics. Protocols are joined with the file by name, neighbor_address or neighbor_as
+type PeerMetadata struct {
+	File    string   `yaml:"file"`
+	JoinBy  string   `yaml:"join_by"`
+	Columns []string `yaml:"columns"`
+}
+
+// Instance is a bird instance collected on every scrape of the metrics endpoint
+type Instance struct {
+	Name   string `yaml:"name"`
+	Socket string `yaml:"socket"`
+	Module string `yaml:"module"`
+}
+
+// Module defines how a bird instance is queried and which metrics are exported
+type Module struct {
+	BirdV2    bool   `yaml:"bird_v2"`
+	IPVersion string `yaml:"ip_version"`
+	Metrics   `yaml:",inline"`
+}
+
+// DefaultMetrics contains the default metric settings
+var DefaultMetrics = Metrics{
+	Protocols:              []string{"bgp", "ospf", "kernel", "static", "direct", "babel", "rpki", "bfd"},
+	NewFormat:              true,
+	DescriptionLabelsRegex: `(\w+)=(\w+)`,
+	Collectors: Collectors{
+		OSPFAreas:   true,
+		BFDSessions: true,
+	},
+	PrefixStats: PrefixStats{
+		Protocols: []string{"bgp", "ospf", "kernel", "static", "direct", "babel"},
+	},
+	Concurrency: 1,
+	BGP: BGP{
+		StaleRoutesFilter: "(65535, 6) ~ bgp_community",
+	},
+}
+
+// DefaultModule contains the defaults applied to every module in the configuration file
+var DefaultModule = Module{
+	IPVersion: "4",
+	Metrics:   DefaultMetrics,
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (m *Module) UnmarshalYAML(value *yaml.Node) error {
+	*m = DefaultModule
+	type plain Module
+
+	return value.Decode((*plain)(m))
+}
+
+// Load reads the configuration file, applies it on top of the defaults and validates the result
+func Load(path string, defaults *Config) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(b, defaults)
+}
+
+// Parse parses a configuration, applies it on top of the defaults and validates the result
+func Parse(b []byte, defaults *Config) (*Config, error) {
+	cfg := &Config{}
+	if defaults != nil {
+		*cfg = *defaults
+		cfg.Modules = make(map[string]*Module, len(defaults.Modules))
+		for name, m := range defaults.Modules {
+			cfg.Modules[name] = m
+		}
+		cfg.Instances = append([]*Instance(nil), defaults.Instances...)
+		cfg.Polling.Intervals = maps.Clone(defaults.Polling.Intervals)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	err := dec.Decode(cfg)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the configuration for errors
+func (cfg *Config) Validate() error {
+	err := cfg.validateBird()
+	if err != nil {
+		return fmt.Errorf("bird: %w", err)
+	}
+
+	err = cfg.Metrics.validate()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.Web.validate()
+	if err != nil {
+		return fmt.Errorf("web: %w", err)
+	}
+
+	err = cfg.Polling.validate()
+	if err != nil {
+		return fmt.Errorf("polling: %w", err)
+	}
+
+	for name, m := range cfg.Modules {
+		err = m.validate()
+		if err != nil {
+			return fmt.Errorf("module %s: %w", name, err)
+		}
+	}
+
+	names := make(map[string]struct{})
+	for _, t := range cfg.ProbeTargets {
+		_, err = client.NewTransport(t, cfg.Bird.Timeout)
+		if err != nil {
+			return fmt.Errorf("probe_targets: %w", err)
+		}
+	}
+
+	for _, i := range cfg.Instances {
+		err = cfg.validateInstance(i)
+		if err != nil {
+			return fmt.Errorf("instance %s: %w", i.Name, err)
+		}
+
+		if _, found := names[i.Name]; found {
+			return fmt.Errorf("instance %s: duplicate name", i.Name)
+		}
+		names[i.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+func (cfg *Config) validateBird() error {
+	if cfg.Bird.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	_, err := client.NewTransport(cfg.Bird.Socket, cfg.Bird.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.Bird.V2 && cfg.Bird.IPv6 {
+		_, err = client.NewTransport(cfg.Bird.Socket6, cfg.Bird.Timeout)
+	}
+
+	return err
+}
+
+func (w *Web) validate() error {
+	if len(w.ListenAddresses) == 0 && !w.SystemdSocket {
+		return fmt.Errorf("no listen address defined")
+	}
+
+	if w.ConfigFile != "" {
+		err := web.Validate(w.ConfigFile)
+		if err != nil {
+			return fmt.Errorf("invalid web config file: %w", err)
+		}
+	}
+
+	for _, p := range []string{w.TelemetryPath, w.ProbePath} {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("invalid path %q", p)
+		}
+	}
+
+	if w.TelemetryPath == w.ProbePath {
+		return fmt.Errorf("telemetry path and probe path must differ")
+	}
+
+	return nil
+}
+
+func (p *Polling) validate() error {
+	if p.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	for name, interval := range p.Intervals {
+		if !slices.Contains(CollectorNames, name) {
+			return fmt.Errorf("unknown collector %q", name)
+		}
+
+		if interval <= 0 {
+			return fmt.Errorf("interval of collector %s must be positive", name)
+		}
+	}
+
+	return nil
+}
+
+// IntervalFor returns the polling interval of the collector
+func (p *Polling) IntervalFor(collector string) time.Duration {
+	if interval, found := p.Intervals[collector]; found {
+		return interval
+	}
+
+	return p.Interval
+}
+
+func (cfg *Config) validateInstance(i *Instance) error {
+	if i.Name == "" {
+		return fmt.Errorf("name is missing")
+	}
+
+	if i.Socket == "" {
+		return fmt.Errorf("socket is missing")
+	}
+
+	_, err := client.NewTransport(i.Socket, cfg.Bird.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if i.Module == "" {
+		i.Module = DefaultModuleName
+	}
+
+	if _, found := cfg.Modules[i.Module]; !found && i.Module != DefaultModuleName {
+		return fmt.Errorf("unknown module %q", i.Module)
+	}
+
+	return nil
+}
+
+func (m *Module) validate() error {
+	if m.IPVersion != "4" && m.IPVersion != "6" {
+		return fmt.Errorf("invalid ip_version %q (expected 4 or 6)", m.IPVersion)
+	}
+
+	return m.Metrics.validate()
+}
+
+func (m *Metrics) validate() error {
+	err := validateProtocols(m.Protocols)
+	if err != nil {
+		return err
+	}
+
+	if m.FormatV3 && !m.NewFormat {
+		return fmt.Errorf("format_v3 requires new_format")
+	}
+
+	err = validateProtocols(m.PrefixStats.Protocols)
+	if err != nil {
+		return fmt.Errorf("prefix_stats: %w", err)
+	}
+
+	err = m.ProtocolFilter.validate()
+	if err != nil {
+		return fmt.Errorf("protocol_filter: %w", err)
+	}
+
+	if m.Collectors.BGPGracefulRestart && m.BGP.StaleRoutesFilter == "" {
+		return fmt.Errorf("bgp: stale_routes_filter must not be empty")
+	}
+
+	for _, asn := range m.BGP.WatchASNs {
+		if asn < 1 || asn > math.MaxUint32 {
+			return fmt.Errorf("bgp: invalid AS number %d in watch_asns", asn)
+		}
+	}
+
+	names := make(map[string]struct{})
+	for _, c := range m.BGP.Communities {
+		err = c.validate()
+		if err != nil {
+			return fmt.Errorf("bgp: communities: %w", err)
+		}
+
+		if _, found := names[c.Name]; found {
+			return fmt.Errorf("bgp: communities: duplicate name %q", c.Name)
+		}
+		names[c.Name] = struct{}{}
+	}
+
+	if m.Blackhole.PrefixInfoLimit < 0 {
+		return fmt.Errorf("blackhole: prefix_info_limit must not be negative")
+	}
+
+	if m.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	re, err := regexp.Compile(m.DescriptionLabelsRegex)
+	if err != nil {
+		return fmt.Errorf("invalid description_labels_regex: %w", err)
+	}
+
+	if m.DescriptionLabels && re.NumSubexp() < 2 {
+		return fmt.Errorf("description_labels_regex must contain two capture groups (key and value)")
+	}
+
+	reserved := m.reservedLabels()
+	for i, k := range m.DescriptionLabelsKeys {
+		if !labelNameRegex.MatchString(k) {
+			return fmt.Errorf("invalid label name %q in description_labels_keys", k)
+		}
+
+		if slices.Contains(reserved, k) {
+			return fmt.Errorf("label %q in description_labels_keys is already exported by the exporter", k)
+		}
+
+		if slices.Contains(m.DescriptionLabelsKeys[:i], k) {
+			return fmt.Errorf("duplicate label %q in description_labels_keys", k)
+		}
+	}
+
+	if m.DescriptionLabels {
+		reserved = append(reserved, m.DescriptionLabelsKeys...)
+	}
+
+	// labels of the name regexes are shared, so they are only reserved for the peer metadata
+	nameLabels := []string{}
+	for _, r := range m.NameLabelsRegexes {
+		err = validateNameLabelsRegex(r, reserved)
+		if err != nil {
+			return fmt.Errorf("name_labels_regexes: %w", err)
+		}
+
+		for _, n := range regexp.MustCompile(r).SubexpNames() {
+			if n != "" {
+				nameLabels = append(nameLabels, n)
+			}
+		}
+	}
+
+	err = m.PeerMetadata.validate(append(reserved, nameLabels...))
+	if err != nil {
+		return fmt.Errorf("peer_metadata: %w", err)
+	}
+
+	return nil
+}
+
+// reservedLabels returns the labels of protocol metrics added by the exporter itself, which must
+// not be used by labels taken from descriptions, protocol names or peer metadata
+func (m *Metrics) reservedLabels() []string {
+	res := slices.Clone(protocolLabels)
+	if m.InfoMetric {
+		res = append(res, "description", "table")
+	}
+
+	return res
+}
+
+func validateNameLabelsRegex(r string, reserved []string) error {
+	re, err := regexp.Compile(r)
+	if err != nil {
+		return err
+	}
+
+	names := 0
+	for _, n := range re.SubexpNames() {
+		if n == "" {
+			continue
+		}
+
+		if !labelNameRegex.MatchString(n) {
+			return fmt.Errorf("invalid label name %q in %q", n, r)
+		}
+
+		if slices.Contains(reserved, n) {
+			return fmt.Errorf("label %q in %q is already exported by the exporter or descriptions", n, r)
+		}
+		names++
+	}
+
+	if names == 0 {
+		return fmt.Errorf("no named capture group in %q", r)
+	}
+
+	return nil
+}
+
+func (p *PeerMetadata) validate(reserved []string) error {
+	if p.File == "" {
+		return nil
+	}
+
+	_, err := os.Stat(p.File)
+	if err != nil {
+		return err
+	}
+
+	if p.JoinBy == "" {
+		p.JoinBy = "name"
+	}
+
+	if !slices.Contains([]string{"name", "neighbor_address", "neighbor_as"}, p.JoinBy) {
+		return fmt.Errorf("invalid join_by %q (expected name, neighbor_address or neighbor_as)", p.JoinBy)
+	}
+
+	if len(p.Columns) == 0 {
+		return fmt.Errorf("no columns defined")
+	}
+
+	for i, c := range p.Columns {
+		if !labelNameRegex.MatchString(c) {
+			return fmt.Errorf("invalid label name %q in columns", c)
+		}
+
+		if slices.Contains(reserved, c) {
+			return fmt.Errorf("label %q in columns is already exported by the exporter, descriptions or names", c)
+		}
+
+		if slices.Contains(p.Columns[:i], c) {
+			return fmt.Errorf("duplicate label %q in columns", c)
+		}
+	}
+
+	return nil
+}
+
+func validateProtocols(names []string) error {
+	for _, p := range names {
+		if _, found := protocolNames[strings.ToLower(p)]; !found {
+			return fmt.Errorf("unknown protocol %q", p)
+		}
+	}
+
+	return nil
+}
+
+// ProbeTargetAllowed returns whether the probe endpoint may query the target. SSH targets execute
+// birdc on the remote host, so they have to be listed in the probe targets
+func (cfg *Config) ProbeTargetAllowed(target string) bool {
+	if len(cfg.ProbeTargets) > 0 {
+		return slices.Contains(cfg.ProbeTargets, target)
+	}
+
+	return !strings.Contains(target, "://") || strings.HasPrefix(target, "unix://") || strings.HasPrefix(target, "tcp://")
+}
+
+// ModuleFor returns the module with the given name. The default module is derived
+// from the global settings unless it is defined explicitly
+func (cfg *Config) ModuleFor(name string) *Module {
+	if m, found := cfg.Modules[name]; found {
+		return m
+	}
+
+	if name != DefaultModuleName {
+		return nil
+	}
+
+	return &Module{
+		BirdV2:    cfg.Bird.V2,
+		IPVersion: "4",
+		Metrics:   cfg.Metrics,
+	}
+}
+
+// EnabledProtocols returns the protocols metrics are exported for
+func (m *Metrics) EnabledProtocols() protocol.Proto {
+	return protoFromNames(m.Protocols)
+}
+
+// PrefixStatsProtocols returns the protocols prefix size statistics are collected for
+func (m *Metrics) PrefixStatsProtocols() protocol.Proto {
+	return protoFromNames(m.PrefixStats.Protocols)
+}
+
+func protoFromNames(names []string) protocol.Proto {
+	res := protocol.Proto(0)
+
+	for _, p := range names {
+		res |= protocolNames[strings.ToLower(p)]
+	}
+
+	return res
+}
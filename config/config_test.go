@@ -0,0 +1,416 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func defaults() *Config {
+	return &Config{
+		Bird: Bird{
+			Socket:  "/var/run/bird.ctl",
+			Socket6: "/var/run/bird6.ctl",
+			IPv4:    true,
+			IPv6:    true,
+			Timeout: 30 * time.Second,
+		},
+		Metrics: DefaultMetrics,
+		Web: Web{
+			ListenAddresses: []string{":9324"},
+			TelemetryPath:   "/metrics",
+			ProbePath:       "/probe",
+		},
+		Polling: Polling{
+			Interval: time.Minute,
+		},
+	}
+}
+
+func TestParseOverridesDefaults(t *testing.T) {
+	b := []byte(`
+bird:
+  socket: ssh://exporter@router1/var/run/bird.ctl
+  v2: true
+protocols: [bgp]
+description_labels: true
+prefix_stats:
+  enabled: true
+  protocols: [bgp]
+collectors:
+  bfd_sessions: false
+web:
+  telemetry_path: /bird
+`)
+
+	cfg, err := Parse(b, defaults())
+	require.NoError(t, err)
+
+	assert.Equal(t, "ssh://exporter@router1/var/run/bird.ctl", cfg.Bird.Socket)
+	assert.Equal(t, "/var/run/bird6.ctl", cfg.Bird.Socket6)
+	assert.True(t, cfg.Bird.V2)
+	assert.True(t, cfg.NewFormat)
+	assert.True(t, cfg.DescriptionLabels)
+	assert.Equal(t, protocol.BGP, cfg.EnabledProtocols())
+	assert.True(t, cfg.PrefixStats.Enabled)
+	assert.Equal(t, protocol.BGP, cfg.PrefixStatsProtocols())
+	assert.True(t, cfg.Collectors.OSPFAreas)
+	assert.False(t, cfg.Collectors.BFDSessions)
+	assert.Equal(t, Web{ListenAddresses: []string{":9324"}, TelemetryPath: "/bird", ProbePath: "/probe"}, cfg.Web)
+
+	m := cfg.ModuleFor(DefaultModuleName)
+	assert.True(t, m.BirdV2)
+	assert.Equal(t, cfg.Metrics, m.Metrics)
+	assert.Nil(t, cfg.ModuleFor("foo"))
+}
+
+func TestParseInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "invalid socket",
+			config: "bird:\n  socket: ftp://router1\n",
+		},
+		{
+			name:   "unknown protocol",
+			config: "protocols: [isis]\n",
+		},
+		{
+			name:   "invalid description regex",
+			config: "description_labels: true\ndescription_labels_regex: \"(\\\\w+\"\n",
+		},
+		{
+			name:   "description regex without value group",
+			config: "description_labels: true\ndescription_labels_regex: \"(\\\\w+)\"\n",
+		},
+		{
+			name:   "no listen address",
+			config: "web:\n  listen_addresses: []\n",
+		},
+		{
+			name:   "missing web config file",
+			config: "web:\n  config_file: /nonexistent/web.yml\n",
+		},
+		{
+			name:   "invalid path",
+			config: "web:\n  telemetry_path: metrics\n",
+		},
+		{
+			name:   "invalid polling interval",
+			config: "polling:\n  interval: 0s\n",
+		},
+		{
+			name:   "empty stale routes filter",
+			config: "collectors:\n  bgp_graceful_restart: true\nbgp:\n  stale_routes_filter: \"\"\n",
+		},
+		{
+			name:   "invalid watched AS number",
+			config: "bgp:\n  watch_asns: [0]\n",
+		},
+		{
+			name:   "invalid community",
+			config: "bgp:\n  communities:\n    - name: blackhole\n      community: \"65535:666\"\n",
+		},
+		{
+			name:   "duplicate community name",
+			config: "bgp:\n  communities:\n    - name: x\n      community: \"(1, 2)\"\n    - name: x\n      community: \"(1, 3)\"\n",
+		},
+		{
+			name:   "negative blackhole prefix info limit",
+			config: "blackhole:\n  prefix_info_limit: -1\n",
+		},
+		{
+			name:   "polling interval of unknown collector",
+			config: "polling:\n  intervals:\n    isis: 5m\n",
+		},
+		{
+			name:   "missing peer metadata file",
+			config: "peer_metadata:\n  file: /nonexistent/peers.csv\n  columns: [provider]\n",
+		},
+		{
+			name:   "name labels regex without named group",
+			config: "name_labels_regexes: ['^(transit)_']\n",
+		},
+		{
+			name:   "description label key of protocol label",
+			config: "description_labels: true\ndescription_labels_keys: [name]\n",
+		},
+		{
+			name:   "description label key of up metric label",
+			config: "description_labels: true\ndescription_labels_keys: [state]\n",
+		},
+		{
+			name:   "description label key of info metric label",
+			config: "description_labels: true\ninfo_metric: true\ndescription_labels_keys: [table]\n",
+		},
+		{
+			name:   "duplicate description label key",
+			config: "description_labels: true\ndescription_labels_keys: [site, site]\n",
+		},
+		{
+			name:   "name labels regex with protocol label",
+			config: "name_labels_regexes: ['^(?P<proto>transit)_']\n",
+		},
+		{
+			name:   "name labels regex with description label key",
+			config: "description_labels: true\ndescription_labels_keys: [site]\nname_labels_regexes: ['^(?P<site>\\w+)_']\n",
+		},
+		{
+			name:   "v3 format without new format",
+			config: "new_format: false\nformat_v3: true\n",
+		},
+		{
+			name:   "invalid timeout",
+			config: "bird:\n  timeout: 0s\n",
+		},
+		{
+			name:   "invalid probe target",
+			config: "probe_targets: [http://router1/]\n",
+		},
+		{
+			name:   "unknown field type",
+			config: "protocols: bgp\n",
+		},
+		{
+			name:   "unknown key",
+			config: "protocol: [bgp]\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.config), defaults())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePolling(t *testing.T) {
+	b := []byte(`
+polling:
+  enabled: true
+  intervals:
+    prefix_size: 5m
+`)
+
+	cfg, err := Parse(b, defaults())
+	require.NoError(t, err)
+
+	assert.True(t, cfg.Polling.Enabled)
+	assert.Equal(t, 5*time.Minute, cfg.Polling.IntervalFor("prefix_size"))
+	assert.Equal(t, time.Minute, cfg.Polling.IntervalFor("protocols"))
+}
+
+func TestParseModules(t *testing.T) {
+	b := []byte(`
+modules:
+  bird2:
+    bird_v2: true
+    protocols: [bgp, BFD]
+  bird6:
+    ip_version: "6"
+    new_format: false
+`)
+
+	cfg, err := Parse(b, defaults())
+	require.NoError(t, err)
+	require.Len(t, cfg.Modules, 2)
+
+	m := cfg.Modules["bird2"]
+	assert.True(t, m.BirdV2)
+	assert.True(t, m.NewFormat)
+	assert.Equal(t, "4", m.IPVersion)
+	assert.Equal(t, protocol.BGP|protocol.BFD, m.EnabledProtocols())
+
+	m = cfg.Modules["bird6"]
+	assert.False(t, m.BirdV2)
+	assert.False(t, m.NewFormat)
+	assert.Equal(t, "6", m.IPVersion)
+	assert.Equal(t, DefaultModule.Protocols, m.Protocols)
+}
+
+func TestParseInvalidModule(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "unknown protocol",
+			config: "modules:\n  foo:\n    protocols: [isis]\n",
+		},
+		{
+			name:   "invalid ip version",
+			config: "modules:\n  foo:\n    ip_version: \"5\"\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.config), defaults())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseInstances(t *testing.T) {
+	b := []byte(`
+modules:
+  bird2:
+    bird_v2: true
+instances:
+  - name: bird-rs1
+    socket: /run/bird/rs1.ctl
+    module: bird2
+  - name: bird-vrf-blue
+    socket: tcp://127.0.0.1:8000
+`)
+
+	cfg, err := Parse(b, defaults())
+	require.NoError(t, err)
+
+	expected := []*Instance{
+		{Name: "bird-rs1", Socket: "/run/bird/rs1.ctl", Module: "bird2"},
+		{Name: "bird-vrf-blue", Socket: "tcp://127.0.0.1:8000", Module: DefaultModuleName},
+	}
+	assert.Equal(t, expected, cfg.Instances)
+}
+
+func TestParseInvalidPeerMetadataColumns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "peers.csv")
+	require.NoError(t, os.WriteFile(file, []byte("neighbor_as,name\n1299,Arelion\n"), 0644))
+
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "protocol label",
+			config: "columns: [name]\n  join_by: neighbor_as\n",
+		},
+		{
+			name:   "info metric label",
+			config: "columns: [description]\ninfo_metric: true\n",
+		},
+		{
+			name:   "description label key",
+			config: "columns: [site]\ndescription_labels: true\ndescription_labels_keys: [site]\n",
+		},
+		{
+			name:   "name label",
+			config: "columns: [role]\nname_labels_regexes: ['^(?P<role>transit)_']\n",
+		},
+		{
+			name:   "duplicate column",
+			config: "columns: [org, org]\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte("peer_metadata:\n  file: "+file+"\n  "+test.config), defaults())
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := Parse([]byte("peer_metadata:\n  file: "+file+"\n  columns: [org]\n"), defaults())
+	assert.NoError(t, err)
+}
+
+func TestParseInvalidInstance(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "missing name",
+			config: "instances:\n  - socket: /run/bird.ctl\n",
+		},
+		{
+			name:   "missing socket",
+			config: "instances:\n  - name: rs1\n",
+		},
+		{
+			name:   "invalid socket",
+			config: "instances:\n  - name: rs1\n    socket: http://rs1/\n",
+		},
+		{
+			name:   "unknown module",
+			config: "instances:\n  - name: rs1\n    socket: /run/bird.ctl\n    module: foo\n",
+		},
+		{
+			name:   "duplicate name",
+			config: "instances:\n  - name: rs1\n    socket: /run/a.ctl\n  - name: rs1\n    socket: /run/b.ctl\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse([]byte(test.config), defaults())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestProbeTargetAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		target  string
+		allowed bool
+	}{
+		{
+			name:    "path",
+			target:  "/run/bird/vrf-blue.ctl",
+			allowed: true,
+		},
+		{
+			name:    "unix socket",
+			target:  "unix:///run/bird/vrf-blue.ctl",
+			allowed: true,
+		},
+		{
+			name:    "tcp",
+			target:  "tcp://10.0.0.1:8000",
+			allowed: true,
+		},
+		{
+			name:    "ssh",
+			target:  "ssh://router1/var/run/bird.ctl?birdc=birdc6",
+			allowed: false,
+		},
+		{
+			name:    "listed ssh target",
+			targets: []string{"ssh://router1/var/run/bird.ctl"},
+			target:  "ssh://router1/var/run/bird.ctl",
+			allowed: true,
+		},
+		{
+			name:    "listed ssh target with other birdc",
+			targets: []string{"ssh://router1/var/run/bird.ctl"},
+			target:  "ssh://router1/var/run/bird.ctl?birdc=birdc6",
+			allowed: false,
+		},
+		{
+			name:    "unlisted path",
+			targets: []string{"ssh://router1/var/run/bird.ctl"},
+			target:  "/run/bird/vrf-blue.ctl",
+			allowed: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := defaults()
+			cfg.ProbeTargets = test.targets
+
+			assert.Equal(t, test.allowed, cfg.ProbeTargetAllowed(test.target))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+)
+
+// ProtocolFilter selects the protocols metrics are exported for by name. Rules defined
+// for a protocol type take precedence over the global rule
+type ProtocolFilter struct {
+	NameFilter `yaml:",inline"`
+	Protocols  map[string]NameFilter `yaml:"protocols"`
+}
+
+// NameFilter defines regular expressions matched against protocol names. A protocol is
+// included if it matches include (or include is empty) and does not match exclude
+type NameFilter struct {
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+}
+
+func (f *ProtocolFilter) validate() error {
+	err := f.NameFilter.validate()
+	if err != nil {
+		return err
+	}
+
+	for name, x := range f.Protocols {
+		if _, found := protocolNames[strings.ToLower(name)]; !found {
+			return fmt.Errorf("unknown protocol %q", name)
+		}
+
+		err = x.validate()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (f *NameFilter) validate() error {
+	_, err := regexp.Compile(f.Include)
+	if err != nil {
+		return fmt.Errorf("invalid include regex: %w", err)
+	}
+
+	_, err = regexp.Compile(f.Exclude)
+	if err != nil {
+		return fmt.Errorf("invalid exclude regex: %w", err)
+	}
+
+	return nil
+}
+
+// ProtocolMatcher is the compiled form of a ProtocolFilter
+type ProtocolMatcher struct {
+	global  *nameMatcher
+	byProto map[protocol.Proto]*nameMatcher
+}
+
+type nameMatcher struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// Matcher compiles the filter. The filter has to be validated before
+func (f *ProtocolFilter) Matcher() *ProtocolMatcher {
+	m := &ProtocolMatcher{
+		global:  f.NameFilter.matcher(),
+		byProto: make(map[protocol.Proto]*nameMatcher),
+	}
+
+	for name, x := range f.Protocols {
+		m.byProto[protocolNames[strings.ToLower(name)]] = x.matcher()
+	}
+
+	return m
+}
+
+func (f *NameFilter) matcher() *nameMatcher {
+	m := &nameMatcher{}
+
+	if f.Include != "" {
+		m.include = regexp.MustCompile(f.Include)
+	}
+
+	if f.Exclude != "" {
+		m.exclude = regexp.MustCompile(f.Exclude)
+	}
+
+	return m
+}
+
+// Matches returns whether metrics are exported for the protocol
+func (m *ProtocolMatcher) Matches(p *protocol.Protocol) bool {
+	if x, found := m.byProto[p.Proto]; found {
+		return x.matches(p.Name)
+	}
+
+	return m.global.matches(p.Name)
+}
+
+func (m *nameMatcher) matches(name string) bool {
+	if m.include != nil && !m.include.MatchString(name) {
+		return false
+	}
+
+	return m.exclude == nil || !m.exclude.MatchString(name)
+}
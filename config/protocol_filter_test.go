@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolMatcher(t *testing.T) {
+	b := []byte(`
+protocol_filter:
+  exclude: ^tmp_
+  protocols:
+    bgp:
+      include: ^(transit|ix)_
+      exclude: _test$
+`)
+
+	cfg, err := Parse(b, defaults())
+	require.NoError(t, err)
+
+	m := cfg.ProtocolFilter.Matcher()
+
+	tests := []struct {
+		name     string
+		proto    protocol.Proto
+		expected bool
+	}{
+		{"transit_as1299", protocol.BGP, true},
+		{"ix_decix", protocol.BGP, true},
+		{"ix_decix_test", protocol.BGP, false},
+		{"customer_as65000", protocol.BGP, false},
+		{"tmp_transit", protocol.BGP, false},
+		{"ospf1", protocol.OSPF, true},
+		{"tmp_static", protocol.Static, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := protocol.NewProtocol(test.name, test.proto, "4", 0)
+			assert.Equal(t, test.expected, m.Matches(p))
+		})
+	}
+}
+
+func TestProtocolMatcherWithoutRules(t *testing.T) {
+	f := &ProtocolFilter{}
+	assert.True(t, f.Matcher().Matches(protocol.NewProtocol("bgp1", protocol.BGP, "4", 0)))
+}
+
+func TestParseInvalidProtocolFilter(t *testing.T) {
+	for _, c := range []string{
+		"protocol_filter:\n  include: \"(\"\n",
+		"protocol_filter:\n  protocols:\n    bgp:\n      exclude: \"[\"\n",
+		"protocol_filter:\n  protocols:\n    isis:\n      include: foo\n",
+	} {
+		_, err := Parse([]byte(c), defaults())
+		assert.Error(t, err, c)
+	}
+}
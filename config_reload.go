@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/czerwonk/bird_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	activeConfig atomic.Pointer[config.Config]
+
+	configReloadSuccessGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "bird_exporter_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful",
+	})
+	configReloadTimestampGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "bird_exporter_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload",
+	})
+)
+
+func currentConfig() *config.Config {
+	return activeConfig.Load()
+}
+
+// configFromFlags returns the configuration defined by command line flags.
+// It is used as base the config file is applied on
+func configFromFlags() *config.Config {
+	return &config.Config{
+		Bird: config.Bird{
+			Socket:  *birdSocket,
+			Socket6: *bird6Socket,
+			V2:      *birdV2,
+			IPv4:    *birdEnabled,
+			IPv6:    *bird6Enabled,
+			Timeout: *birdTimeout,
+		},
+		Metrics: config.Metrics{
+			Protocols:                   enabledProtocolNames(),
+			NewFormat:                   *newFormat,
+			FormatV3:                    *formatV3,
+			InfoMetric:                  *infoMetric,
+			DescriptionLabels:           *descriptionLabels,
+			DescriptionLabelsRegex:      *descriptionLabelsRegex,
+			DescriptionLabelsConsistent: *descriptionLabelsConsistent,
+			DescriptionLabelsKeys:       descriptionLabelKeysFromFlag(),
+			NameLabelsRegexes:           *nameLabelsRegexes,
+			Collectors: config.Collectors{
+				OSPFAreas:          config.DefaultMetrics.Collectors.OSPFAreas,
+				BFDSessions:        config.DefaultMetrics.Collectors.BFDSessions,
+				BGPCapabilities:    *bgpCapabilities,
+				BGPGracefulRestart: *bgpGracefulRestart,
+				NextHops:           *nextHops,
+			},
+			BGP: config.BGP{
+				StaleRoutesFilter: config.DefaultMetrics.BGP.StaleRoutesFilter,
+				WatchASNs:         *watchASNs,
+			},
+			Blackhole: config.Blackhole{
+				Enabled:         *blackholeEnabled,
+				PrefixInfoLimit: *blackholeInfoLimit,
+			},
+			PrefixStats: config.PrefixStats{
+				Enabled:   *enablePrefixSize,
+				Protocols: config.DefaultMetrics.PrefixStats.Protocols,
+				Table:     *enableTablePrefixSize,
+			},
+			Concurrency: *concurrency,
+			ProtocolFilter: config.ProtocolFilter{
+				NameFilter: config.NameFilter{
+					Include: *protoInclude,
+					Exclude: *protoExclude,
+				},
+			},
+		},
+		Web: config.Web{
+			ListenAddresses: listenAddressesOrDefault(),
+			ConfigFile:      *webConfigFile,
+			SystemdSocket:   *systemdSocket,
+			TelemetryPath:   *metricsPath,
+			ProbePath:       *probePath,
+		},
+		Polling: config.Polling{
+			Enabled:  *pollingEnabled,
+			Interval: *pollingInterval,
+		},
+		StateTracking: config.StateTracking{
+			Enabled: *stateTracking,
+			File:    *stateFile,
+		},
+		Logs: config.Logs{
+			File:   *logFile,
+			Socket: *logSocket,
+		},
+	}
+}
+
+func listenAddressesOrDefault() []string {
+	if len(*listenAddresses) == 0 {
+		return []string{":9324"}
+	}
+
+	return *listenAddresses
+}
+
+func descriptionLabelKeysFromFlag() []string {
+	if *descriptionLabelsKeys == "" {
+		return nil
+	}
+
+	return strings.Split(*descriptionLabelsKeys, ",")
+}
+
+// asnsFlag is a flag containing a comma separated list of AS numbers which can be specified multiple times
+type asnsFlag []int64
+
+func (f *asnsFlag) String() string {
+	s := make([]string, len(*f))
+	for i, asn := range *f {
+		s[i] = strconv.FormatInt(asn, 10)
+	}
+
+	return strings.Join(s, ",")
+}
+
+func (f *asnsFlag) Set(value string) error {
+	for _, x := range strings.Split(value, ",") {
+		asn, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(x), "AS"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid AS number %q", x)
+		}
+
+		*f = append(*f, asn)
+	}
+
+	return nil
+}
+
+func enabledProtocolNames() []string {
+	res := []string{}
+
+	for _, x := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"bgp", *enableBGP},
+		{"ospf", *enableOSPF},
+		{"kernel", *enableKernel},
+		{"static", *enableStatic},
+		{"direct", *enableDirect},
+		{"babel", *enableBabel},
+		{"rpki", *enableRPKI},
+		{"bfd", *enableBFD},
+	} {
+		if x.enabled {
+			res = append(res, x.name)
+		}
+	}
+
+	return res
+}
+
+func loadConfig() (*config.Config, error) {
+	cfg := configFromFlags()
+	if *configFile == "" {
+		return cfg, cfg.Validate()
+	}
+
+	return config.Load(*configFile, cfg)
+}
+
+func reloadConfig() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Errorf("could not load config: %v", err)
+		configReloadSuccessGauge.Set(0)
+		return err
+	}
+
+	err = updateStateStore(&cfg.StateTracking)
+	if err != nil {
+		log.Errorf("could not load protocol states: %v", err)
+		configReloadSuccessGauge.Set(0)
+		return err
+	}
+
+	prev := activeConfig.Swap(cfg)
+	if prev != nil && !reflect.DeepEqual(prev.Web, cfg.Web) {
+		log.Warn("changes of web settings require a restart to be applied")
+	}
+
+	restartPolling(cfg)
+
+	if prev == nil || prev.Logs != cfg.Logs {
+		restartLogWatcher(&cfg.Logs)
+	}
+
+	configReloadSuccessGauge.Set(1)
+	configReloadTimestampGauge.SetToCurrentTime()
+
+	if prev != nil {
+		log.Info("config reloaded")
+	}
+
+	return nil
+}
+
+func reloadOnSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	for range ch {
+		reloadConfig()
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/czerwonk/bird_exporter/birdlog"
+	"github.com/czerwonk/bird_exporter/config"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	logWatcherMu     sync.Mutex
+	logWatcherCancel context.CancelFunc
+)
+
+// restartLogWatcher stops counting events from the previous log sources and starts watching the configured ones
+func restartLogWatcher(cfg *config.Logs) {
+	logWatcherMu.Lock()
+	defer logWatcherMu.Unlock()
+
+	if logWatcherCancel != nil {
+		logWatcherCancel()
+		logWatcherCancel = nil
+	}
+
+	if cfg.File == "" && cfg.Socket == "" {
+		return
+	}
+
+	if cfg.File != "" {
+		log.Infof("Counting events from bird log file %s", cfg.File)
+	}
+
+	if cfg.Socket != "" {
+		log.Infof("Counting events from bird log messages received on %s", cfg.Socket)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logWatcherCancel = cancel
+	birdlog.Watch(ctx, cfg.File, cfg.Socket)
+}
@@ -3,12 +3,22 @@ package main
 import (
 	"flag"
 	"fmt"
+	"html"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
-	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/czerwonk/bird_exporter/birdlog"
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/config"
+	"github.com/czerwonk/bird_exporter/parser"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	toolkit "github.com/prometheus/exporter-toolkit/web"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -16,14 +26,18 @@ const version string = "1.4.3"
 
 var (
 	showVersion      = flag.Bool("version", false, "Print version information.")
-	listenAddress    = flag.String("web.listen-address", ":9324", "Address on which to expose metrics and web interface.")
+	listenAddresses  = &stringsFlag{}
+	webConfigFile    = flag.String("web.config.file", "", "Path to configuration file that can enable TLS or authentication. See: https://github.com/prometheus/exporter-toolkit/blob/master/docs/web-configuration.md")
+	systemdSocket    = flag.Bool("web.systemd-socket", false, "Use systemd socket activation listeners instead of port listeners (Linux only).")
 	metricsPath      = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
-	birdSocket       = flag.String("bird.socket", "/var/run/bird.ctl", "Socket to communicate with bird routing daemon")
+	birdSocket       = flag.String("bird.socket", "/var/run/bird.ctl", "Socket to communicate with bird routing daemon (path, unix://, tcp://host:port or ssh://user@host/path)")
 	birdV2           = flag.Bool("bird.v2", false, "Bird major version >= 2.0 (multi channel protocols)")
-	tlsEnabled       = flag.Bool("tls.enabled", false, "Enables TLS")
-	tlsCertChainPath = flag.String("tls.cert-file", "", "Path to TLS cert file")
-	tlsKeyPath       = flag.String("tls.key-file", "", "Path to TLS key file")
+	tlsEnabled       = flag.Bool("tls.enabled", false, "Enables TLS (deprecated, use -web.config.file)")
+	tlsCertChainPath = flag.String("tls.cert-file", "", "Path to TLS cert file (deprecated, use -web.config.file)")
+	tlsKeyPath       = flag.String("tls.key-file", "", "Path to TLS key file (deprecated, use -web.config.file)")
 	newFormat        = flag.Bool("format.new", true, "New metric format (more convenient / generic)")
+	formatV3         = flag.Bool("format.v3", false, "Export route changes as counters (_total) and the protocol start time instead of the uptime (requires -format.new)")
+	infoMetric       = flag.Bool("format.info-metric", false, "Export protocol labels in bird_protocol_info, other protocol metrics only get the name and ip_version labels (new format only)")
 	enableBGP        = flag.Bool("proto.bgp", true, "Enables metrics for protocol BGP")
 	enableOSPF       = flag.Bool("proto.ospf", true, "Enables metrics for protocol OSPF")
 	enableKernel     = flag.Bool("proto.kernel", true, "Enables metrics for protocol Kernel")
@@ -32,7 +46,11 @@ var (
 	enableBabel      = flag.Bool("proto.babel", true, "Enables metrics for protocol Babel")
 	enableRPKI       = flag.Bool("proto.rpki", true, "Enables metrics for protocol RPKI")
 	enableBFD        = flag.Bool("proto.bfd", true, "Enables metrics for protocol BFD")
+	protoInclude     = flag.String("proto.include-regex", "", "Only export metrics for protocols with names matching the regex")
+	protoExclude     = flag.String("proto.exclude-regex", "", "Do not export metrics for protocols with names matching the regex")
 	enablePrefixSize = flag.Bool("prefix.size", false, "Enables prefix size statistics collection per protocol")
+	concurrency      = flag.Int("bird.concurrency", 1, "Maximum number of collectors querying bird concurrently within a scrape")
+	birdTimeout      = flag.Duration("bird.timeout", 30*time.Second, "Maximum duration of a query to a remote bird instance (tcp:// or ssh://)")
 	enableTablePrefixSize = flag.Bool("prefix.size.table", false, "Enables prefix size statistics collection for entire routing table (unique prefixes)")
 	// pre bird 2.0
 	bird6Socket            = flag.String("bird.socket6", "/var/run/bird6.ctl", "Socket to communicate with bird6 routing daemon (not compatible with -bird.v2)")
@@ -40,9 +58,43 @@ var (
 	bird6Enabled           = flag.Bool("bird.ipv6", true, "Get protocols from bird6 (not compatible with -bird.v2)")
 	descriptionLabels      = flag.Bool("format.description-labels", false, "Add labels from protocol descriptions.")
 	descriptionLabelsRegex = flag.String("format.description-labels-regex", "(\\w+)=(\\w+)", "Regex to extract labels from protocol description")
+	descriptionLabelsConsistent = flag.Bool("format.description-labels-consistent", false, "Export the description labels of all protocols for every protocol (missing labels are empty)")
+	descriptionLabelsKeys  = flag.String("format.description-labels-keys", "", "Comma separated list of description labels exported for every protocol (missing labels are empty)")
+	nameLabelsRegexes      = &stringsFlag{}
+	watchASNs              = &asnsFlag{}
+	probePath              = flag.String("web.probe-path", "/probe", "Path under which to expose metrics of arbitrary bird instances (multi target exporter pattern)")
+	pollingEnabled         = flag.Bool("polling.enabled", false, "Run collectors in background and serve the last snapshot on scrape")
+	pollingInterval        = flag.Duration("polling.interval", time.Minute, "Interval collectors are run in background polling mode")
+	stateTracking          = flag.Bool("state.tracking", false, "Track state changes (e.g. session resets) of protocols across scrapes")
+	stateFile              = flag.String("state.file", "", "Path to file the tracked protocol states are persisted to (optional)")
+	bgpCapabilities        = flag.Bool("collector.bgp-capabilities", false, "Export the capabilities of established BGP sessions")
+	bgpGracefulRestart     = flag.Bool("collector.bgp-graceful-restart", false, "Export the graceful restart state and stale routes of BGP sessions")
+	nextHops               = flag.Bool("collector.next-hops", false, "Export the number of routes per next hop and the ECMP width of the routes of the protocols")
+	blackholeEnabled       = flag.Bool("blackhole.enabled", false, "Export the number of blackhole routes of BGP and static protocols")
+	blackholeInfoLimit     = flag.Int("blackhole.prefix-info-limit", 0, "Maximum number of blackholed prefixes exported as info series per protocol (0 disables the series)")
+	logFile                = flag.String("log.file", "", "Path to bird log file to count protocol events from (optional)")
+	logSocket              = flag.String("log.socket", "", "Path to unix datagram socket receiving bird log messages from syslog (optional)")
+	configFile             = flag.String("config.file", "", "Path to YAML config file (settings in the file take precedence over flags, reloaded on SIGHUP or POST /-/reload)")
 )
 
+// exporterRegistry holds the metrics describing the exporter itself (independent of the scraped bird instances)
+var exporterRegistry = prometheus.NewRegistry()
+
 func init() {
+	exporterRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		configReloadSuccessGauge,
+		configReloadTimestampGauge,
+	)
+	exporterRegistry.MustRegister(client.Collectors()...)
+	exporterRegistry.MustRegister(parser.Collectors()...)
+	exporterRegistry.MustRegister(birdlog.Collectors()...)
+
+	flag.Var(watchASNs, "bgp.watch-asns", "Comma separated list of AS numbers the routes of BGP sessions are counted for by origin and first AS. Repeatable")
+	flag.Var(nameLabelsRegexes, "format.name-labels-regex", "Regex with named capture groups to extract labels from protocol names. Repeatable, the first matching regex is used")
+	flag.Var(listenAddresses, "web.listen-address", "Address on which to expose metrics and web interface. Repeatable for multiple addresses. (default :9324)")
+
 	flag.Usage = func() {
 		fmt.Println("Usage: bird_exporter [ ... ]\n\nParameters:")
 		fmt.Println()
@@ -58,6 +110,13 @@ func main() {
 		os.Exit(0)
 	}
 
+	err := reloadConfig()
+	if err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
+
+	go reloadOnSignal()
+
 	startServer()
 }
 
@@ -71,73 +130,166 @@ func printVersion() {
 func startServer() {
 	log.Infof("Starting bird exporter (Version: %s)", version)
 
-	if !*newFormat {
+	cfg := currentConfig()
+	if !cfg.NewFormat {
 		log.Info("INFO: You are using the old metric format. Please consider using the new (more convenient one) by setting -format.new=true.")
 	}
 
+	web := cfg.Web
 	http.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
 		w.Write([]byte(`<html>
 			<head><title>Bird Routing Daemon Exporter (Version ` + version + `)</title></head>
 			<body>
 			<h1>Bird Routing Daemon Exporter</h1>
-			<p><a href="` + *metricsPath + `">Metrics</a></p>
+			<p><a href="` + web.TelemetryPath + `">Metrics</a></p>
+			<p><a href="` + html.EscapeString(web.ProbePath+"?target="+url.QueryEscape(cfg.Bird.Socket)) + `">Probe ` + html.EscapeString(cfg.Bird.Socket) + `</a></p>
 			<h2>More information:</h2>
 			<p><a href="https://github.com/czerwonk/bird_exporter">github.com/czerwonk/bird_exporter</a></p>
 			</body>
 			</html>`))
 	})
-	http.HandleFunc(*metricsPath, handleMetricsRequest)
+	http.HandleFunc(web.TelemetryPath, handleMetricsRequest)
+	http.HandleFunc(web.ProbePath, handleProbeRequest)
+	http.HandleFunc("/-/reload", handleReloadRequest)
 
-	log.Infof("Listening for %s on %s (TLS: %v)", *metricsPath, *listenAddress, *tlsEnabled)
+	configFile := web.ConfigFile
 	if *tlsEnabled {
-		log.Fatal(http.ListenAndServeTLS(*listenAddress, *tlsCertChainPath, *tlsKeyPath, nil))
-		return
+		if configFile != "" {
+			log.Fatal("-tls.enabled can not be combined with a web config file")
+		}
+
+		log.Warn("-tls.enabled is deprecated, please use -web.config.file instead")
+
+		var err error
+		configFile, err = legacyTLSWebConfig(*tlsCertChainPath, *tlsKeyPath)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	log.Infof("Listening for %s on %s", web.TelemetryPath, strings.Join(web.ListenAddresses, ", "))
+	flags := &toolkit.FlagConfig{
+		WebListenAddresses: &web.ListenAddresses,
+		WebSystemdSocket:   &web.SystemdSocket,
+		WebConfigFile:      &configFile,
+	}
+	log.Fatal(toolkit.ListenAndServe(&http.Server{}, flags, slog.Default()))
 }
 
 func handleMetricsRequest(w http.ResponseWriter, r *http.Request) {
+	cfg := currentConfig()
+	p := activePolling.Load()
 	reg := prometheus.NewRegistry()
-	p := enabledProtocols()
-	c := NewMetricCollector(*newFormat, p, *descriptionLabels)
-	reg.MustRegister(c)
 
-	l := log.New()
-	l.Level = log.ErrorLevel
-	promhttp.HandlerFor(reg, promhttp.HandlerOpts{
-		ErrorLog:      l,
-		ErrorHandling: promhttp.ContinueOnError,
-	}).ServeHTTP(w, r)
-}
+	if len(cfg.Instances) == 0 {
+		var c scrapeCollector
+		if p != nil {
+			c = p.collectorFor("")
+		} else {
+			c = collectorForInstance(cfg, nil)
+		}
 
-func enabledProtocols() protocol.Proto {
-	res := protocol.Proto(0)
+		f, err := collectorFilterFromRequest(r, c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-	if *enableBGP {
-		res |= protocol.BGP
+		reg.MustRegister(f.apply(c))
+		serveMetrics(prometheus.Gatherers{reg, exporterRegistry}, w, r, cfg.FormatV3)
+		return
 	}
-	if *enableOSPF {
-		res |= protocol.OSPF
+
+	collectors := make([]scrapeCollector, len(cfg.Instances))
+	for idx, i := range cfg.Instances {
+		if p != nil {
+			collectors[idx] = p.collectorFor(i.Name)
+		} else {
+			collectors[idx] = collectorForInstance(cfg, i)
+		}
 	}
-	if *enableKernel {
-		res |= protocol.Kernel
+
+	f, err := collectorFilterFromRequest(r, collectors...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if *enableStatic {
-		res |= protocol.Static
+
+	// collectors of all instances are collected concurrently by the registry
+	openMetrics := false
+	for idx, i := range cfg.Instances {
+		prometheus.WrapRegistererWith(prometheus.Labels{"instance_name": i.Name}, reg).MustRegister(f.apply(collectors[idx]))
+		openMetrics = openMetrics || cfg.ModuleFor(i.Module).FormatV3
 	}
-	if *enableDirect {
-		res |= protocol.Direct
+
+	serveMetrics(prometheus.Gatherers{reg, exporterRegistry}, w, r, openMetrics)
+}
+
+func handleProbeRequest(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
 	}
-	if *enableBabel {
-		res |= protocol.Babel
+
+	cfg := currentConfig()
+	if !cfg.ProbeTargetAllowed(target) {
+		http.Error(w, fmt.Sprintf("target %q is not allowed", target), http.StatusForbidden)
+		return
+	}
+
+	_, err := client.NewTransport(target, cfg.Bird.Timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	moduleName := r.URL.Query().Get("module")
+	if moduleName == "" {
+		moduleName = config.DefaultModuleName
 	}
-	if *enableRPKI {
-		res |= protocol.RPKI
+
+	m := cfg.ModuleFor(moduleName)
+	if m == nil {
+		http.Error(w, fmt.Sprintf("unknown module %q", moduleName), http.StatusBadRequest)
+		return
 	}
-	if *enableBFD {
-		res |= protocol.BFD
+
+	c := NewMetricCollector(clientForTarget(target, m, cfg.Bird.Timeout), m)
+	f, err := collectorFilterFromRequest(r, c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	return res
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(f.apply(c))
+	serveMetrics(reg, w, r, m.FormatV3)
+}
+
+func handleReloadRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := reloadConfig()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not reload config: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// serveMetrics serves the metrics of the gatherer. When openMetrics is set the OpenMetrics format
+// (including created timestamps of counters) is used if accepted by the client
+func serveMetrics(g prometheus.Gatherer, w http.ResponseWriter, r *http.Request, openMetrics bool) {
+	l := log.New()
+	l.Level = log.ErrorLevel
+	promhttp.HandlerFor(g, promhttp.HandlerOpts{
+		ErrorLog:                            l,
+		ErrorHandling:                       promhttp.ContinueOnError,
+		EnableOpenMetrics:                   openMetrics,
+		EnableOpenMetricsTextCreatedSamples: openMetrics,
+	}).ServeHTTP(w, r)
 }
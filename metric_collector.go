@@ -1,78 +1,153 @@
 package main
 
 import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/birdlog"
 	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/config"
 	"github.com/czerwonk/bird_exporter/metrics"
 	"github.com/czerwonk/bird_exporter/protocol"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	protocolsCollector          = "protocols"
+	ospfCollector               = "ospf"
+	bfdCollector                = "bfd"
+	prefixSizeCollector         = "prefix_size"
+	tablePrefixSizeCollector    = "table_prefix_size"
+	bgpCapabilitiesCollector    = "bgp_capabilities"
+	bgpGracefulRestartCollector = "bgp_graceful_restart"
+	bgpASRoutesCollector        = "bgp_as_routes"
+	bgpCommunitiesCollector     = "bgp_communities"
+	blackholeCollector          = "blackhole"
+	nextHopsCollector           = "next_hops"
+)
+
+// namedExporter is a MetricExporter identified by the name of the collector it belongs to
+type namedExporter struct {
+	name string
+	metrics.MetricExporter
+}
+
 type MetricCollector struct {
-	exporters        map[protocol.Proto][]metrics.MetricExporter
+	exporters        map[protocol.Proto][]namedExporter
 	client           *client.BirdClient
 	enabledProtocols protocol.Proto
+	protocolMatcher  *config.ProtocolMatcher
+	labelStrategy    metrics.LabelStrategy
+	tracker          *stateTracker
+	logProtocols     *birdlog.ProtocolNames
 	newFormat        bool
+	concurrency      int
 }
 
-func NewMetricCollector(newFormat bool, enabledProtocols protocol.Proto, descriptionLabels bool) *MetricCollector {
-	c := getClient()
-	var e map[protocol.Proto][]metrics.MetricExporter
+func NewMetricCollector(c *client.BirdClient, m *config.Module) *MetricCollector {
+	var e map[protocol.Proto][]namedExporter
+	var l metrics.LabelStrategy
 
-	if newFormat {
-		e = exportersForDefault(c, descriptionLabels)
+	if m.NewFormat {
+		l = labelStrategyForDefault(&m.Metrics)
+		e = exportersForDefault(c, &m.Metrics, l)
 	} else {
-		e = exportersForLegacy(c)
+		l = metrics.NewLegacyLabelStrategy()
+		e = exportersForLegacy(c, &m.Metrics, l)
 	}
 
 	return &MetricCollector{
 		exporters:        e,
 		client:           c,
-		enabledProtocols: enabledProtocols,
-		newFormat:        newFormat,
+		enabledProtocols: m.EnabledProtocols(),
+		protocolMatcher:  m.ProtocolFilter.Matcher(),
+		labelStrategy:    l,
+		newFormat:        m.NewFormat,
+		concurrency:      m.Concurrency,
+	}
+}
+
+func getClient(cfg *config.Config) *client.BirdClient {
+	o := &client.BirdClientOptions{
+		BirdSocket:   cfg.Bird.Socket,
+		Bird6Socket:  cfg.Bird.Socket6,
+		Bird6Enabled: cfg.Bird.IPv6,
+		BirdEnabled:  cfg.Bird.IPv4,
+		BirdV2:       cfg.Bird.V2,
+		Timeout:      cfg.Bird.Timeout,
 	}
+
+	return &client.BirdClient{Options: o}
 }
 
-func getClient() *client.BirdClient {
+// collectorForInstance returns the collector of an instance of the metrics endpoint (nil for the default instance)
+func collectorForInstance(cfg *config.Config, i *config.Instance) *MetricCollector {
+	if i == nil {
+		c := NewMetricCollector(getClient(cfg), cfg.ModuleFor(config.DefaultModuleName))
+		c.tracker = stateTrackerFor("")
+		c.logProtocols = birdlog.ProtocolNamesFor("")
+		return c
+	}
+
+	m := cfg.ModuleFor(i.Module)
+	c := NewMetricCollector(clientForTarget(i.Socket, m, cfg.Bird.Timeout), m)
+	c.tracker = stateTrackerFor(i.Name)
+	c.logProtocols = birdlog.ProtocolNamesFor(i.Name)
+	return c
+}
+
+func clientForTarget(target string, m *config.Module, timeout time.Duration) *client.BirdClient {
 	o := &client.BirdClientOptions{
-		BirdSocket:   *birdSocket,
-		Bird6Socket:  *bird6Socket,
-		Bird6Enabled: *bird6Enabled,
-		BirdEnabled:  *birdEnabled,
-		BirdV2:       *birdV2,
+		BirdSocket:   target,
+		Bird6Socket:  target,
+		BirdEnabled:  m.IPVersion == "4",
+		Bird6Enabled: m.IPVersion == "6",
+		BirdV2:       m.BirdV2,
+		Timeout:      timeout,
 	}
 
 	return &client.BirdClient{Options: o}
 }
 
-func exportersForLegacy(c *client.BirdClient) map[protocol.Proto][]metrics.MetricExporter {
-	l := metrics.NewLegacyLabelStrategy()
-	prefixExporter := metrics.NewPrefixSizeExporter("bird", c)
-	tablePrefixExporter := metrics.NewTablePrefixSizeExporter("bird", c)
+func exportersForLegacy(c *client.BirdClient, cfg *config.Metrics, l metrics.LabelStrategy) map[protocol.Proto][]namedExporter {
+	prefixExporter := namedExporter{prefixSizeCollector, metrics.NewPrefixSizeExporter("bird", c)}
+	tablePrefixExporter := namedExporter{tablePrefixSizeCollector, metrics.NewTablePrefixSizeExporter("bird", c)}
+
+	exporters := map[protocol.Proto][]namedExporter{
+		protocol.BGP:    {{protocolsCollector, metrics.NewLegacyMetricExporter("bgp4_session", "bgp6_session", l)}},
+		protocol.Direct: {{protocolsCollector, metrics.NewLegacyMetricExporter("direct4", "direct6", l)}},
+		protocol.Kernel: {{protocolsCollector, metrics.NewLegacyMetricExporter("kernel4", "kernel6", l)}},
+		protocol.OSPF:   {{protocolsCollector, metrics.NewLegacyMetricExporter("ospf", "ospfv3", l)}},
+		protocol.Static: {{protocolsCollector, metrics.NewLegacyMetricExporter("static4", "static6", l)}},
+		protocol.Babel:  {{protocolsCollector, metrics.NewLegacyMetricExporter("babel4", "babel6", l)}},
+		protocol.RPKI:   {{protocolsCollector, metrics.NewLegacyMetricExporter("rpki4", "rpki6", l)}},
+		protocol.BFD:    {},
+	}
+
+	if cfg.Collectors.OSPFAreas {
+		exporters[protocol.OSPF] = append(exporters[protocol.OSPF], namedExporter{ospfCollector, metrics.NewOSPFExporter("", c)})
+	}
 
-	exporters := map[protocol.Proto][]metrics.MetricExporter{
-		protocol.BGP:    {metrics.NewLegacyMetricExporter("bgp4_session", "bgp6_session", l)},
-		protocol.Direct: {metrics.NewLegacyMetricExporter("direct4", "direct6", l)},
-		protocol.Kernel: {metrics.NewLegacyMetricExporter("kernel4", "kernel6", l)},
-		protocol.OSPF:   {metrics.NewLegacyMetricExporter("ospf", "ospfv3", l), metrics.NewOSPFExporter("", c)},
-		protocol.Static: {metrics.NewLegacyMetricExporter("static4", "static6", l)},
-		protocol.Babel:  {metrics.NewLegacyMetricExporter("babel4", "babel6", l)},
-		protocol.RPKI:   {metrics.NewLegacyMetricExporter("rpki4", "rpki6", l)},
-		protocol.BFD:    {metrics.NewBFDExporter(c)},
+	if cfg.Collectors.BFDSessions {
+		exporters[protocol.BFD] = append(exporters[protocol.BFD], namedExporter{bfdCollector, metrics.NewBFDExporter(c)})
 	}
 
-	// Add per-protocol prefix size exporter 
-	if *enablePrefixSize {
+	addOptionalExporters(exporters, c, cfg)
+
+	// Add per-protocol prefix size exporter
+	if cfg.PrefixStats.Enabled {
+		prefixProtocols := cfg.PrefixStatsProtocols()
 		for proto := range exporters {
-			if proto == protocol.BGP || proto == protocol.OSPF || proto == protocol.Kernel || 
-			   proto == protocol.Static || proto == protocol.Direct || proto == protocol.Babel {
+			if prefixProtocols&proto == proto {
 				exporters[proto] = append(exporters[proto], prefixExporter)
 			}
 		}
 	}
 
 	// Add table-wide prefix size exporter (only needs to run once per IP version)
-	if *enableTablePrefixSize {
+	if cfg.PrefixStats.Table {
 		// Add to BGP protocols since they're most likely to exist
 		if _, exists := exporters[protocol.BGP]; exists {
 			exporters[protocol.BGP] = append(exporters[protocol.BGP], tablePrefixExporter)
@@ -82,35 +157,105 @@ func exportersForLegacy(c *client.BirdClient) map[protocol.Proto][]metrics.Metri
 	return exporters
 }
 
-func exportersForDefault(c *client.BirdClient, descriptionLabels bool) map[protocol.Proto][]metrics.MetricExporter {
-	l := metrics.NewDefaultLabelStrategy(descriptionLabels, *descriptionLabelsRegex)
-	e := metrics.NewGenericProtocolMetricExporter("bird_protocol", true, l)
-	prefixExporter := metrics.NewPrefixSizeExporter("bird", c)
-	tablePrefixExporter := metrics.NewTablePrefixSizeExporter("bird", c)
+func labelStrategyForDefault(cfg *config.Metrics) metrics.LabelStrategy {
+	l := metrics.NewDefaultLabelStrategy(cfg.DescriptionLabels, cfg.DescriptionLabelsRegex)
+
+	if len(cfg.DescriptionLabelsKeys) > 0 {
+		l = l.WithDescriptionLabelKeys(cfg.DescriptionLabelsKeys)
+	} else if cfg.DescriptionLabelsConsistent {
+		l = l.WithConsistentDescriptionLabels().WithReservedLabels(reservedDescriptionLabels(cfg))
+	}
+
+	var res metrics.LabelStrategy = l
+	if len(cfg.NameLabelsRegexes) > 0 {
+		res = metrics.NewNameRegexLabelStrategy(res, cfg.NameLabelsRegexes)
+	}
+
+	if cfg.PeerMetadata.File != "" {
+		md := cfg.PeerMetadata
+		res = metrics.NewPeerMetadataLabelStrategy(res, peerMetadataFile(md.File), md.JoinBy, md.Columns)
+	}
+
+	return res
+}
+
+// reservedDescriptionLabels returns the labels description labels must not override
+func reservedDescriptionLabels(cfg *config.Metrics) []string {
+	res := []string{}
+	if cfg.InfoMetric {
+		res = append(res, "description", "table")
+	}
+
+	for _, r := range cfg.NameLabelsRegexes {
+		for _, n := range regexp.MustCompile(r).SubexpNames() {
+			if n != "" {
+				res = append(res, n)
+			}
+		}
+	}
+
+	if cfg.PeerMetadata.File != "" {
+		res = append(res, cfg.PeerMetadata.Columns...)
+	}
+
+	return res
+}
+
+func exportersForDefault(c *client.BirdClient, cfg *config.Metrics, l metrics.LabelStrategy) map[protocol.Proto][]namedExporter {
+	protocolExporter := func(l metrics.LabelStrategy) metrics.MetricExporter {
+		if cfg.FormatV3 {
+			return metrics.NewProtocolMetricExporterV3("bird_protocol", l)
+		}
+
+		return metrics.NewGenericProtocolMetricExporter("bird_protocol", true, l)
+	}
+
+	var e []namedExporter
+	if cfg.InfoMetric {
+		// labels are only exported by the info metric, numeric metrics are identified by name and IP version
+		e = []namedExporter{
+			{protocolsCollector, metrics.NewProtocolInfoExporter("bird_protocol", l)},
+			{protocolsCollector, protocolExporter(metrics.NewIdentityLabelStrategy())},
+		}
+	} else {
+		e = []namedExporter{{protocolsCollector, protocolExporter(l)}}
+	}
+	prefixExporter := namedExporter{prefixSizeCollector, metrics.NewPrefixSizeExporter("bird", c)}
+	tablePrefixExporter := namedExporter{tablePrefixSizeCollector, metrics.NewTablePrefixSizeExporter("bird", c)}
+
+	exporters := map[protocol.Proto][]namedExporter{
+		protocol.BGP:    e,
+		protocol.Direct: e,
+		protocol.Kernel: e,
+		protocol.OSPF:   e,
+		protocol.Static: e,
+		protocol.Babel:  e,
+		protocol.RPKI:   e,
+		protocol.BFD:    {},
+	}
 
-	exporters := map[protocol.Proto][]metrics.MetricExporter{
-		protocol.BGP:    {e},
-		protocol.Direct: {e},
-		protocol.Kernel: {e},
-		protocol.OSPF:   {e, metrics.NewOSPFExporter("bird_", c)},
-		protocol.Static: {e},
-		protocol.Babel:  {e},
-		protocol.RPKI:   {e},
-		protocol.BFD:    {metrics.NewBFDExporter(c)},
+	if cfg.Collectors.OSPFAreas {
+		exporters[protocol.OSPF] = append(exporters[protocol.OSPF], namedExporter{ospfCollector, metrics.NewOSPFExporter("bird_", c)})
 	}
 
+	if cfg.Collectors.BFDSessions {
+		exporters[protocol.BFD] = append(exporters[protocol.BFD], namedExporter{bfdCollector, metrics.NewBFDExporter(c)})
+	}
+
+	addOptionalExporters(exporters, c, cfg)
+
 	// Add per-protocol prefix size exporter
-	if *enablePrefixSize {
+	if cfg.PrefixStats.Enabled {
+		prefixProtocols := cfg.PrefixStatsProtocols()
 		for proto := range exporters {
-			if proto == protocol.BGP || proto == protocol.OSPF || proto == protocol.Kernel || 
-			   proto == protocol.Static || proto == protocol.Direct || proto == protocol.Babel {
+			if prefixProtocols&proto == proto {
 				exporters[proto] = append(exporters[proto], prefixExporter)
 			}
 		}
 	}
 
 	// Add table-wide prefix size exporter (only needs to run once per IP version)
-	if *enableTablePrefixSize {
+	if cfg.PrefixStats.Table {
 		// Add to BGP protocols since they're most likely to exist
 		if _, exists := exporters[protocol.BGP]; exists {
 			exporters[protocol.BGP] = append(exporters[protocol.BGP], tablePrefixExporter)
@@ -120,16 +265,34 @@ func exportersForDefault(c *client.BirdClient, descriptionLabels bool) map[proto
 	return exporters
 }
 
-var socketQueryDesc = prometheus.NewDesc(
-	"bird_socket_query_success",
-	"Result of querying bird socket: 0 = failed, 1 = suceeded",
-	nil,
-	nil,
+var (
+	socketQueryDesc = prometheus.NewDesc(
+		"bird_socket_query_success",
+		"Result of querying bird socket: 0 = failed, 1 = suceeded",
+		nil,
+		nil,
+	)
+	collectorDurationDesc = prometheus.NewDesc(
+		"bird_exporter_collector_duration_seconds",
+		"Time spent by a collector during the scrape",
+		[]string{"collector"},
+		nil,
+	)
+	collectorSuccessDesc = prometheus.NewDesc(
+		"bird_exporter_collector_success",
+		"Result of a collector during the scrape: 0 = failed, 1 = succeeded",
+		[]string{"collector"},
+		nil,
+	)
 )
 
 func (m *MetricCollector) Describe(ch chan<- *prometheus.Desc) {
 
 	ch <- socketQueryDesc
+	ch <- collectorDurationDesc
+	ch <- collectorSuccessDesc
+	ch <- stateChangesDesc
+	ch <- lastStateChangeDesc
 
 	for _, v := range m.exporters {
 		for _, e := range v {
@@ -139,27 +302,248 @@ func (m *MetricCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (m *MetricCollector) Collect(ch chan<- prometheus.Metric) {
+	m.collect(ch, func(string) bool { return true })
+}
+
+// collect runs the collectors accepted by include
+func (m *MetricCollector) collect(ch chan<- prometheus.Metric, include func(name string) bool) {
+	r := newCollectorResults()
+	defer r.export(ch)
 
+	start := time.Now()
 	protocols, err := m.client.GetProtocols()
 
-	var queryResult float64 = 1
-	if err != nil {
-		queryResult = 0
+	if include(protocolsCollector) {
+		r.add(protocolsCollector, time.Since(start), err)
+
+		var queryResult float64 = 1
+		if err != nil {
+			queryResult = 0
+		}
+		ch <- prometheus.MustNewConstMetric(socketQueryDesc, prometheus.GaugeValue, queryResult)
 	}
-	ch <- prometheus.MustNewConstMetric(socketQueryDesc, prometheus.GaugeValue, queryResult)
 
 	if err != nil {
 		log.Errorln(err)
+
+		// all other collectors depend on the protocols and fail as well
+		for _, name := range m.collectorNames() {
+			if name != protocolsCollector && include(name) {
+				r.add(name, 0, err)
+			}
+		}
+
 		return
 	}
 
+	exported := make([]*protocol.Protocol, 0, len(protocols))
 	for _, p := range protocols {
-		if p.Proto == protocol.PROTO_UNKNOWN || (m.enabledProtocols&p.Proto != p.Proto) {
+		if p.Proto == protocol.PROTO_UNKNOWN || (m.enabledProtocols&p.Proto != p.Proto) || !m.protocolMatcher.Matches(p) {
 			continue
 		}
 
+		exported = append(exported, p)
+	}
+
+	if m.tracker != nil && include(protocolsCollector) {
+		m.tracker.track(exported, ch)
+	}
+
+	if m.logProtocols != nil {
+		names := make([]string, len(exported))
+		for i, p := range exported {
+			names[i] = p.Name
+		}
+		m.logProtocols.Set(names)
+	}
+
+	if s, ok := m.labelStrategy.(metrics.ScrapeLabelStrategy); ok {
+		err = s.Prepare(exported)
+		if err != nil {
+			log.Errorln(err)
+		}
+	}
+
+	jobs := make([]*exportJob, 0)
+	for _, p := range exported {
 		for _, e := range m.exporters[p.Proto] {
-			e.Export(p, ch, m.newFormat)
+			if include(e.name) {
+				jobs = append(jobs, &exportJob{protocol: p, exporter: e})
+			}
 		}
 	}
+
+	runExportJobs(jobs, m.concurrency, m.newFormat)
+
+	// results are processed in the order of the jobs to keep the output deterministic
+	for _, j := range jobs {
+		r.add(j.exporter.name, j.duration, j.err)
+
+		if j.err != nil {
+			log.Errorln(j.err)
+		}
+
+		for _, metric := range j.metrics {
+			ch <- metric
+		}
+	}
+}
+
+// collectorNames returns the names of the collectors used for the enabled protocols
+func (m *MetricCollector) collectorNames() []string {
+	used := map[string]bool{protocolsCollector: true}
+	for proto, exporters := range m.exporters {
+		if m.enabledProtocols&proto != proto {
+			continue
+		}
+
+		for _, e := range exporters {
+			used[e.name] = true
+		}
+	}
+
+	names := make([]string, 0, len(used))
+	for _, name := range config.CollectorNames {
+		if used[name] {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// exportJob is the execution of an exporter for a single protocol
+type exportJob struct {
+	protocol *protocol.Protocol
+	exporter namedExporter
+	metrics  []prometheus.Metric
+	duration time.Duration
+	err      error
+}
+
+// runExportJobs runs the jobs using a pool of at most concurrency workers
+func runExportJobs(jobs []*exportJob, concurrency int, newFormat bool) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	queue := make(chan *exportJob)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < concurrency && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range queue {
+				j.run(newFormat)
+			}
+		}()
+	}
+
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+
+	wg.Wait()
+}
+
+func (j *exportJob) run(newFormat bool) {
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+
+	go func() {
+		for m := range ch {
+			j.metrics = append(j.metrics, m)
+		}
+		close(done)
+	}()
+
+	start := time.Now()
+	j.err = j.exporter.Export(j.protocol, ch, newFormat)
+	j.duration = time.Since(start)
+
+	close(ch)
+	<-done
+}
+
+// collectorResults aggregates duration and success of each collector within a scrape
+type collectorResults struct {
+	names    []string
+	duration map[string]time.Duration
+	failed   map[string]bool
+}
+
+func newCollectorResults() *collectorResults {
+	return &collectorResults{
+		duration: make(map[string]time.Duration),
+		failed:   make(map[string]bool),
+	}
+}
+
+func (r *collectorResults) add(name string, d time.Duration, err error) {
+	if _, found := r.duration[name]; !found {
+		r.names = append(r.names, name)
+	}
+
+	r.duration[name] += d
+	r.failed[name] = r.failed[name] || err != nil
+}
+
+func (r *collectorResults) export(ch chan<- prometheus.Metric) {
+	for _, name := range r.names {
+		var success float64 = 1
+		if r.failed[name] {
+			success = 0
+		}
+
+		ch <- prometheus.MustNewConstMetric(collectorDurationDesc, prometheus.GaugeValue, r.duration[name].Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(collectorSuccessDesc, prometheus.GaugeValue, success, name)
+	}
+}
+
+// addOptionalExporters adds the exporters of the optional collectors, which are the same for all metric formats
+func addOptionalExporters(exporters map[protocol.Proto][]namedExporter, c *client.BirdClient, cfg *config.Metrics) {
+	add := func(e namedExporter, protocols ...protocol.Proto) {
+		for _, proto := range protocols {
+			exporters[proto] = append(exporters[proto], e)
+		}
+	}
+
+	if cfg.Collectors.BGPCapabilities {
+		add(namedExporter{bgpCapabilitiesCollector, metrics.NewBGPCapabilitiesExporter()}, protocol.BGP)
+	}
+
+	if cfg.Collectors.BGPGracefulRestart {
+		add(namedExporter{bgpGracefulRestartCollector, metrics.NewBGPGracefulRestartExporter(c, cfg.BGP.StaleRoutesFilter)}, protocol.BGP)
+	}
+
+	if len(cfg.BGP.WatchASNs) > 0 {
+		add(namedExporter{bgpASRoutesCollector, metrics.NewBGPASRoutesExporter(c, cfg.BGP.WatchASNs)}, protocol.BGP)
+	}
+
+	if len(cfg.BGP.Communities) > 0 {
+		add(namedExporter{bgpCommunitiesCollector, metrics.NewBGPCommunityRoutesExporter(c, communityConditions(cfg.BGP.Communities))}, protocol.BGP, protocol.Static)
+	}
+
+	if cfg.Blackhole.Enabled {
+		add(namedExporter{blackholeCollector, metrics.NewBlackholeExporter(c, cfg.Blackhole.PrefixInfoLimit)}, protocol.BGP, protocol.Static)
+	}
+
+	if cfg.Collectors.NextHops {
+		add(namedExporter{nextHopsCollector, metrics.NewNextHopExporter(c)}, protocol.BGP, protocol.OSPF, protocol.Static, protocol.Kernel, protocol.Direct, protocol.Babel)
+	}
+}
+
+func communityConditions(patterns []config.CommunityPattern) []metrics.CommunityCondition {
+	conditions := make([]metrics.CommunityCondition, len(patterns))
+	for i, p := range patterns {
+		conditions[i] = metrics.CommunityCondition{
+			Name:      p.Name,
+			Condition: p.Condition(),
+		}
+	}
+
+	return conditions
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/config"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDesc = prometheus.NewDesc("test_value", "Test value", []string{"name"}, nil)
+
+type slowExporter struct {
+	running    atomic.Int32
+	maxRunning atomic.Int32
+}
+
+func (e *slowExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- testDesc
+}
+
+func (e *slowExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	n := e.running.Add(1)
+	defer e.running.Add(-1)
+
+	for {
+		max := e.maxRunning.Load()
+		if n <= max || e.maxRunning.CompareAndSwap(max, n) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if p.Name == "broken" {
+		return errors.New("query failed")
+	}
+
+	ch <- prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, float64(p.Uptime), p.Name)
+	ch <- prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, float64(p.Uptime+1), p.Name)
+	return nil
+}
+
+func TestRunExportJobs(t *testing.T) {
+	e := &slowExporter{}
+	names := []string{"bgp1", "bgp2", "broken", "bgp3", "bgp4", "bgp5", "bgp6", "bgp7"}
+
+	jobs := make([]*exportJob, 0)
+	for i, name := range names {
+		p := protocol.NewProtocol(name, protocol.BGP, "4", i*10)
+		jobs = append(jobs, &exportJob{protocol: p, exporter: namedExporter{"test", e}})
+	}
+
+	runExportJobs(jobs, 3, true)
+
+	assert.Equal(t, int32(3), e.maxRunning.Load(), "max concurrent exporters")
+
+	for i, j := range jobs {
+		if j.protocol.Name == "broken" {
+			assert.Error(t, j.err)
+			assert.Empty(t, j.metrics)
+			continue
+		}
+
+		require.NoError(t, j.err)
+		require.Len(t, j.metrics, 2)
+		assert.Equal(t, float64(i*10), metricValue(t, j.metrics[0]), "metric order")
+		assert.Equal(t, float64(i*10+1), metricValue(t, j.metrics[1]), "metric order")
+		assert.True(t, j.duration >= 10*time.Millisecond)
+	}
+}
+
+func TestCollectorResults(t *testing.T) {
+	r := newCollectorResults()
+	r.add("protocols", time.Second, nil)
+	r.add("ospf", time.Second, errors.New("failed"))
+	r.add("ospf", 2*time.Second, nil)
+
+	ch := make(chan prometheus.Metric, 10)
+	r.export(ch)
+	close(ch)
+
+	values := []float64{}
+	for m := range ch {
+		values = append(values, metricValue(t, m))
+	}
+
+	assert.Equal(t, []float64{1, 1, 3, 0}, values)
+}
+
+func TestOptionalExportersOfAllFormats(t *testing.T) {
+	cfg := config.DefaultMetrics
+	cfg.Collectors.BGPCapabilities = true
+	cfg.Blackhole.Enabled = true
+	cfg.Collectors.NextHops = true
+
+	for _, newFormat := range []bool{false, true} {
+		cfg.NewFormat = newFormat
+		m := NewMetricCollector(&client.BirdClient{Options: &client.BirdClientOptions{}}, &config.Module{Metrics: cfg})
+
+		names := m.collectorNames()
+		assert.Subset(t, names, []string{bgpCapabilitiesCollector, blackholeCollector, nextHopsCollector})
+		assert.NotContains(t, names, bgpGracefulRestartCollector)
+		assert.Len(t, m.exporters[protocol.Kernel], 2, "new format %v", newFormat)
+	}
+}
+
+func metricValue(t *testing.T, m prometheus.Metric) float64 {
+	pb := &dto.Metric{}
+	require.NoError(t, m.Write(pb))
+
+	return pb.GetGauge().GetValue()
+}
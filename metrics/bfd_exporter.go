@@ -4,7 +4,6 @@ import (
 	"github.com/czerwonk/bird_exporter/client"
 	"github.com/czerwonk/bird_exporter/protocol"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -39,20 +38,21 @@ func (m *bfdMetricExporter) Describe(ch chan<- *prometheus.Desc) {
 	ch <- bfdTimoutDesc
 }
 
-func (m *bfdMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) {
+func (m *bfdMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
 	if p.Proto != protocol.BFD {
-		return
+		return nil
 	}
 
 	sessions, err := m.client.GetBFDSessions(p)
 	if err != nil {
-		log.Errorln(err)
-		return
+		return err
 	}
 
 	for _, s := range sessions {
 		m.exportSession(s, p.Name, ch)
 	}
+
+	return nil
 }
 
 func (m *bfdMetricExporter) exportSession(s *protocol.BFDSession, protocolName string, ch chan<- prometheus.Metric) {
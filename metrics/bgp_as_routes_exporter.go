@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	originASRoutesDesc = prometheus.NewDesc("bird_bgp_origin_as_routes", "Number of routes of the BGP session originated by the AS", []string{"name", "ip_version", "origin_as"}, nil)
+	firstASRoutesDesc  = prometheus.NewDesc("bird_bgp_first_as_routes", "Number of routes of the BGP session with the AS as first AS in the path", []string{"name", "ip_version", "first_as"}, nil)
+)
+
+// BGPASRoutesExporter exports the number of routes of BGP sessions by origin and first AS for a list of watched AS numbers
+type BGPASRoutesExporter struct {
+	client client.Client
+	asns   []int64
+}
+
+// NewBGPASRoutesExporter creates a new instance of BGPASRoutesExporter
+func NewBGPASRoutesExporter(c client.Client, asns []int64) *BGPASRoutesExporter {
+	return &BGPASRoutesExporter{
+		client: c,
+		asns:   asns,
+	}
+}
+
+func (m *BGPASRoutesExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- originASRoutesDesc
+	ch <- firstASRoutesDesc
+}
+
+func (m *BGPASRoutesExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	if p.Up == 0 {
+		return nil
+	}
+
+	for _, asn := range m.asns {
+		as := strconv.FormatInt(asn, 10)
+
+		origin, err := m.client.GetRouteCount(p, "bgp_path.last = "+as)
+		if err != nil {
+			return fmt.Errorf("failed to count routes of protocol %s originated by AS%s: %w", p.Name, as, err)
+		}
+
+		first, err := m.client.GetRouteCount(p, "bgp_path.first = "+as)
+		if err != nil {
+			return fmt.Errorf("failed to count routes of protocol %s with first AS%s: %w", p.Name, as, err)
+		}
+
+		ch <- prometheus.MustNewConstMetric(originASRoutesDesc, prometheus.GaugeValue, float64(origin), p.Name, p.IPVersion, as)
+		ch <- prometheus.MustNewConstMetric(firstASRoutesDesc, prometheus.GaugeValue, float64(first), p.Name, p.IPVersion, as)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBGPASRoutesExporter(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	p.Up = 1
+
+	c := &routeCountClient{counts: map[string]int64{
+		"bgp_path.last = 65000":      3,
+		"bgp_path.first = 65000":     5,
+		"bgp_path.last = 4200000000": 7,
+	}}
+	e := NewBGPASRoutesExporter(c, []int64{65000, 4200000000})
+
+	metrics := map[string]float64{}
+	for _, m := range export(t, e, p) {
+		metrics[m.name+"/"+m.label("origin_as")+m.label("first_as")] = m.GetGauge().GetValue()
+	}
+
+	assert.Equal(t, []string{
+		"bgp_path.last = 65000",
+		"bgp_path.first = 65000",
+		"bgp_path.last = 4200000000",
+		"bgp_path.first = 4200000000",
+	}, c.conditions)
+	assert.Equal(t, map[string]float64{
+		"bird_bgp_origin_as_routes/65000":      3,
+		"bird_bgp_first_as_routes/65000":       5,
+		"bird_bgp_origin_as_routes/4200000000": 7,
+		"bird_bgp_first_as_routes/4200000000":  0,
+	}, metrics)
+}
+
+func TestBGPASRoutesExporterSessionDown(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 0)
+
+	c := &routeCountClient{}
+	metrics := export(t, NewBGPASRoutesExporter(c, []int64{65000}), p)
+
+	assert.Empty(t, c.conditions)
+	assert.Empty(t, metrics)
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"slices"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BGPCapabilities contains the capabilities exported for every established BGP session
+var BGPCapabilities = []string{
+	"multiprotocol",
+	"route_refresh",
+	"enhanced_refresh",
+	"extended_next_hop",
+	"extended_message",
+	"graceful_restart",
+	"long_lived_graceful_restart",
+	"as4",
+	"add_path_rx",
+	"add_path_tx",
+}
+
+var (
+	bgpCapabilityDesc           = prometheus.NewDesc("bird_bgp_capability", "Whether the capability is announced (1) or not (0) by the local side or the neighbor of the BGP session", []string{"name", "ip_version", "capability", "side"}, nil)
+	bgpCapabilityNegotiatedDesc = prometheus.NewDesc("bird_bgp_capability_negotiated", "Whether the capability is used in the BGP session (1) or not (0)", []string{"name", "ip_version", "capability"}, nil)
+)
+
+// BGPCapabilitiesExporter exports the capabilities of established BGP sessions
+type BGPCapabilitiesExporter struct {
+}
+
+// NewBGPCapabilitiesExporter creates a new instance of BGPCapabilitiesExporter
+func NewBGPCapabilitiesExporter() *BGPCapabilitiesExporter {
+	return &BGPCapabilitiesExporter{}
+}
+
+func (m *BGPCapabilitiesExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bgpCapabilityDesc
+	ch <- bgpCapabilityNegotiatedDesc
+}
+
+func (m *BGPCapabilitiesExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	// capabilities are only listed for established sessions (bird 1.x only lists the neighbor ones)
+	hasLocal := len(p.LocalCapabilities) > 0
+	if !hasLocal && len(p.NeighborCapabilities) == 0 {
+		return nil
+	}
+
+	for _, c := range BGPCapabilities {
+		local := slices.Contains(p.LocalCapabilities, c)
+		neighbor := slices.Contains(p.NeighborCapabilities, c)
+
+		if hasLocal {
+			ch <- prometheus.MustNewConstMetric(bgpCapabilityDesc, prometheus.GaugeValue, boolToFloat(local), p.Name, p.IPVersion, c, "local")
+		}
+		ch <- prometheus.MustNewConstMetric(bgpCapabilityDesc, prometheus.GaugeValue, boolToFloat(neighbor), p.Name, p.IPVersion, c, "neighbor")
+
+		if hasLocal {
+			negotiated := negotiatedCapability(c, p)
+			ch <- prometheus.MustNewConstMetric(bgpCapabilityNegotiatedDesc, prometheus.GaugeValue, boolToFloat(negotiated), p.Name, p.IPVersion, c)
+		}
+	}
+
+	return nil
+}
+
+// negotiatedCapability checks if both sides announced the capability. ADD-PATH is negotiated per
+// direction: receiving multiple paths requires the neighbor to be able to send them and vice versa
+func negotiatedCapability(c string, p *protocol.Protocol) bool {
+	remote := c
+	switch c {
+	case "add_path_rx":
+		remote = "add_path_tx"
+	case "add_path_tx":
+		remote = "add_path_rx"
+	}
+
+	return slices.Contains(p.LocalCapabilities, c) && slices.Contains(p.NeighborCapabilities, remote)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
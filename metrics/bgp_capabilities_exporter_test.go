@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBGPCapabilitiesExporter(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	p.LocalCapabilities = []string{"multiprotocol", "graceful_restart", "add_path_rx", "add_path_tx"}
+	p.NeighborCapabilities = []string{"multiprotocol", "add_path_tx"}
+
+	ch := make(chan prometheus.Metric, 100)
+	require.NoError(t, NewBGPCapabilitiesExporter().Export(p, ch, true))
+	close(ch)
+
+	metrics := map[string]float64{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+		metrics[metricName(m)+"/"+labelValue(pb, "capability")+"/"+labelValue(pb, "side")] = pb.GetGauge().GetValue()
+	}
+
+	assert.Len(t, metrics, 3*len(BGPCapabilities))
+	assert.Equal(t, float64(1), metrics["bird_bgp_capability/graceful_restart/local"])
+	assert.Equal(t, float64(0), metrics["bird_bgp_capability/graceful_restart/neighbor"])
+	assert.Equal(t, float64(0), metrics["bird_bgp_capability_negotiated/graceful_restart/"])
+	assert.Equal(t, float64(1), metrics["bird_bgp_capability_negotiated/multiprotocol/"])
+	assert.Equal(t, float64(1), metrics["bird_bgp_capability_negotiated/add_path_rx/"])
+	assert.Equal(t, float64(0), metrics["bird_bgp_capability_negotiated/add_path_tx/"])
+}
+
+func TestBGPCapabilitiesExporterSameNameBothDaemons(t *testing.T) {
+	// bird 1.x runs separate daemons for IPv4 and IPv6, sessions are often named the same
+	ch := make(chan prometheus.Metric, 100)
+	for _, ipVersion := range []string{"4", "6"} {
+		p := protocol.NewProtocol("bgp1", protocol.BGP, ipVersion, 3600)
+		p.NeighborCapabilities = []string{"route_refresh", "as4"}
+		require.NoError(t, NewBGPCapabilitiesExporter().Export(p, ch, true))
+	}
+	close(ch)
+
+	series := map[string]struct{}{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+
+		key := metricName(m) + "/" + labelValue(pb, "ip_version") + "/" + labelValue(pb, "capability") + "/" + labelValue(pb, "side")
+		assert.NotContains(t, series, key)
+		series[key] = struct{}{}
+	}
+
+	assert.Len(t, series, 2*len(BGPCapabilities))
+}
+
+func TestBGPCapabilitiesExporterNotEstablished(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 0)
+
+	ch := make(chan prometheus.Metric, 100)
+	require.NoError(t, NewBGPCapabilitiesExporter().Export(p, ch, true))
+	close(ch)
+
+	assert.Empty(t, ch)
+}
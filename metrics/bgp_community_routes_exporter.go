@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var communityRoutesDesc = prometheus.NewDesc("bird_bgp_community_routes", "Number of routes of the protocol tagged with a community matching the pattern", []string{"name", "ip_version", "table", "community"}, nil)
+
+// CommunityCondition is a named bird filter condition matching routes by (large) community
+type CommunityCondition struct {
+	Name      string
+	Condition string
+}
+
+// BGPCommunityRoutesExporter exports the number of routes per protocol and table matching community patterns
+type BGPCommunityRoutesExporter struct {
+	client     client.Client
+	conditions []CommunityCondition
+}
+
+// NewBGPCommunityRoutesExporter creates a new instance of BGPCommunityRoutesExporter
+func NewBGPCommunityRoutesExporter(c client.Client, conditions []CommunityCondition) *BGPCommunityRoutesExporter {
+	return &BGPCommunityRoutesExporter{
+		client:     c,
+		conditions: conditions,
+	}
+}
+
+func (m *BGPCommunityRoutesExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- communityRoutesDesc
+}
+
+func (m *BGPCommunityRoutesExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	if p.Up == 0 {
+		return nil
+	}
+
+	for _, c := range m.conditions {
+		count, err := m.client.GetRouteCount(p, c.Condition)
+		if err != nil {
+			return fmt.Errorf("failed to count routes of protocol %s with community %s: %w", p.Name, c.Name, err)
+		}
+
+		ch <- prometheus.MustNewConstMetric(communityRoutesDesc, prometheus.GaugeValue, float64(count), p.Name, p.IPVersion, p.Table, c.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBGPCommunityRoutesExporter(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	p.Up = 1
+	p.Table = "master4"
+
+	c := &routeCountClient{counts: map[string]int64{
+		"bgp_community ~ [(65535, 666)]":        7,
+		"bgp_large_community ~ [(65000, 1, *)]": 12,
+	}}
+	e := NewBGPCommunityRoutesExporter(c, []CommunityCondition{
+		{Name: "blackhole", Condition: "bgp_community ~ [(65535, 666)]"},
+		{Name: "region_eu", Condition: "bgp_large_community ~ [(65000, 1, *)]"},
+	})
+
+	metrics := map[string]float64{}
+	for _, m := range export(t, e, p) {
+		assert.Equal(t, "master4", m.label("table"))
+		metrics[m.label("community")] = m.GetGauge().GetValue()
+	}
+
+	assert.Equal(t, []string{"bgp_community ~ [(65535, 666)]", "bgp_large_community ~ [(65000, 1, *)]"}, c.conditions)
+	assert.Equal(t, map[string]float64{"blackhole": 7, "region_eu": 12}, metrics)
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	gracefulRestartActiveDesc = prometheus.NewDesc("bird_bgp_graceful_restart_active", "Whether a graceful restart of the neighbor is in progress (1) or not (0)", []string{"name", "ip_version"}, nil)
+	gracefulRestartTimeDesc   = prometheus.NewDesc("bird_bgp_graceful_restart_time_seconds", "Restart time announced by the neighbor in the graceful restart capability", []string{"name", "ip_version"}, nil)
+	staleRoutesDesc           = prometheus.NewDesc("bird_bgp_stale_routes", "Number of routes of the neighbor kept as stale during graceful restart", []string{"name", "ip_version"}, nil)
+)
+
+// BGPGracefulRestartExporter exports the graceful restart state of BGP sessions and the number of stale routes
+type BGPGracefulRestartExporter struct {
+	client            client.Client
+	staleRoutesFilter string
+}
+
+// NewBGPGracefulRestartExporter creates a new instance of BGPGracefulRestartExporter. Stale routes are counted
+// using the filter condition while a graceful restart is in progress
+func NewBGPGracefulRestartExporter(c client.Client, staleRoutesFilter string) *BGPGracefulRestartExporter {
+	return &BGPGracefulRestartExporter{
+		client:            c,
+		staleRoutesFilter: staleRoutesFilter,
+	}
+}
+
+func (m *BGPGracefulRestartExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- gracefulRestartActiveDesc
+	ch <- gracefulRestartTimeDesc
+	ch <- staleRoutesDesc
+}
+
+func (m *BGPGracefulRestartExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	// routes are only kept stale while the restart is in progress, so bird is not queried otherwise
+	var stale int64
+	if p.GracefulRestartActive {
+		var err error
+		stale, err = m.client.GetRouteCount(p, m.staleRoutesFilter)
+		if err != nil {
+			return fmt.Errorf("failed to count stale routes of protocol %s: %w", p.Name, err)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(gracefulRestartActiveDesc, prometheus.GaugeValue, boolToFloat(p.GracefulRestartActive), p.Name, p.IPVersion)
+	ch <- prometheus.MustNewConstMetric(staleRoutesDesc, prometheus.GaugeValue, float64(stale), p.Name, p.IPVersion)
+
+	if p.GracefulRestartTime > 0 {
+		ch <- prometheus.MustNewConstMetric(gracefulRestartTimeDesc, prometheus.GaugeValue, float64(p.GracefulRestartTime), p.Name, p.IPVersion)
+	}
+
+	return nil
+}
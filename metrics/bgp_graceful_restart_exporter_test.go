@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBGPGracefulRestartExporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		active  bool
+		queries int
+		stale   float64
+	}{
+		{
+			name:    "restart in progress",
+			active:  true,
+			queries: 1,
+			stale:   42,
+		},
+		{
+			name:    "no restart",
+			active:  false,
+			queries: 0,
+			stale:   0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+			p.GracefulRestartActive = test.active
+			p.GracefulRestartTime = 120
+
+			c := &routeCountClient{counts: map[string]int64{"(65535, 6) ~ bgp_community": 42}}
+			e := NewBGPGracefulRestartExporter(c, "(65535, 6) ~ bgp_community")
+
+			metrics := map[string]float64{}
+			for _, m := range export(t, e, p) {
+				metrics[m.name] = m.GetGauge().GetValue()
+			}
+
+			assert.Len(t, c.conditions, test.queries)
+			assert.Equal(t, boolToFloat(test.active), metrics["bird_bgp_graceful_restart_active"])
+			assert.Equal(t, test.stale, metrics["bird_bgp_stale_routes"])
+			assert.Equal(t, float64(120), metrics["bird_bgp_graceful_restart_time_seconds"])
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BlackholeCondition matches routes tagged with the BLACKHOLE community (RFC 7999) or with blackhole destination
+const BlackholeCondition = "(65535, 666) ~ bgp_community || dest = RTD_BLACKHOLE"
+
+var (
+	blackholeRoutesDesc    = prometheus.NewDesc("bird_blackhole_routes", "Number of blackhole routes of the protocol", []string{"name", "ip_version", "table"}, nil)
+	blackholeRouteInfoDesc = prometheus.NewDesc("bird_blackhole_route_info", "Blackhole route of the protocol", []string{"name", "ip_version", "table", "prefix"}, nil)
+)
+
+// BlackholeExporter exports the number of blackhole routes per protocol and optionally the blackholed prefixes
+type BlackholeExporter struct {
+	client          client.Client
+	prefixInfoLimit int
+}
+
+// NewBlackholeExporter creates a new instance of BlackholeExporter. At most prefixInfoLimit prefixes are exported per protocol
+func NewBlackholeExporter(c client.Client, prefixInfoLimit int) *BlackholeExporter {
+	return &BlackholeExporter{
+		client:          c,
+		prefixInfoLimit: prefixInfoLimit,
+	}
+}
+
+func (m *BlackholeExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- blackholeRoutesDesc
+	ch <- blackholeRouteInfoDesc
+}
+
+func (m *BlackholeExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	if p.Up == 0 {
+		return nil
+	}
+
+	count := 0
+	prefixes := make(map[string]struct{})
+	err := m.client.GetRoutes(p, BlackholeCondition, func(r *protocol.Route) {
+		count++
+
+		if _, found := prefixes[r.Network]; !found && len(prefixes) < m.prefixInfoLimit {
+			prefixes[r.Network] = struct{}{}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get blackhole routes of protocol %s: %w", p.Name, err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(blackholeRoutesDesc, prometheus.GaugeValue, float64(count), p.Name, p.IPVersion, p.Table)
+
+	for prefix := range prefixes {
+		ch <- prometheus.MustNewConstMetric(blackholeRouteInfoDesc, prometheus.GaugeValue, 1, p.Name, p.IPVersion, p.Table, prefix)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlackholeExporter(t *testing.T) {
+	routes := []*protocol.Route{
+		{Network: "192.0.2.1/32", Protocol: "bgp1", Table: "master4"},
+		{Network: "192.0.2.1/32", Protocol: "bgp1", Table: "master4"},
+		{Network: "192.0.2.2/32", Protocol: "bgp1", Table: "master4"},
+		{Network: "192.0.2.3/32", Protocol: "bgp1", Table: "master4"},
+	}
+
+	tests := []struct {
+		name     string
+		limit    int
+		prefixes int
+	}{
+		{
+			name:     "without prefix info",
+			limit:    0,
+			prefixes: 0,
+		},
+		{
+			name:     "limited prefix info",
+			limit:    2,
+			prefixes: 2,
+		},
+		{
+			name:     "all prefixes",
+			limit:    10,
+			prefixes: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+			p.Up = 1
+			p.Table = "master4"
+
+			c := &routeCountClient{routes: routes}
+			e := NewBlackholeExporter(c, test.limit)
+
+			var count float64
+			prefixes := 0
+			for _, m := range export(t, e, p) {
+				switch m.name {
+				case "bird_blackhole_routes":
+					count = m.GetGauge().GetValue()
+				case "bird_blackhole_route_info":
+					prefixes++
+				}
+			}
+
+			assert.Equal(t, []string{BlackholeCondition}, c.conditions)
+			assert.Equal(t, float64(4), count)
+			assert.Equal(t, test.prefixes, prefixes)
+		})
+	}
+}
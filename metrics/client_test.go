@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+// routeCountClient returns the route count configured for each condition and the given routes
+// for every route query. The conditions of all queries are recorded
+type routeCountClient struct {
+	client.Client
+	conditions []string
+	counts     map[string]int64
+	routes     []*protocol.Route
+}
+
+func (c *routeCountClient) GetRouteCount(p *protocol.Protocol, condition string) (int64, error) {
+	c.conditions = append(c.conditions, condition)
+	return c.counts[condition], nil
+}
+
+func (c *routeCountClient) GetRoutes(p *protocol.Protocol, condition string, fn func(*protocol.Route)) error {
+	c.conditions = append(c.conditions, condition)
+	for _, r := range c.routes {
+		fn(r)
+	}
+
+	return nil
+}
+
+// exportedMetric is a metric written by an exporter
+type exportedMetric struct {
+	*dto.Metric
+	name string
+}
+
+func (m *exportedMetric) label(name string) string {
+	return labelValue(m.Metric, name)
+}
+
+// export runs the exporter for the protocol and returns the exported metrics
+func export(t *testing.T, e MetricExporter, p *protocol.Protocol) []*exportedMetric {
+	t.Helper()
+
+	ch := make(chan prometheus.Metric, 100)
+	require.NoError(t, e.Export(p, ch, true))
+	close(ch)
+
+	res := []*exportedMetric{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+		res = append(res, &exportedMetric{Metric: pb, name: metricName(m)})
+	}
+
+	return res
+}
@@ -2,28 +2,105 @@ package metrics
 
 import (
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/czerwonk/bird_exporter/protocol"
+	log "github.com/sirupsen/logrus"
 )
 
+// exporterLabels are the labels added to protocol metrics by the exporter itself
+var exporterLabels = []string{"name", "proto", "ip_version", "import_filter", "export_filter", "state"}
+
 // DefaultLabelStrategy defines the labels to add to an metric and its data retrieval method
 type DefaultLabelStrategy struct {
 	descriptionLabels      bool
 	descriptionLabelsRegex *regexp.Regexp
+	consistentLabels       bool
+	fixedKeys              bool
+	reservedLabels         []string
+	mu                     sync.RWMutex
+	descriptionLabelKeys   []string
+	skippedKeys            map[string]bool
 }
 
 func NewDefaultLabelStrategy(descriptionLabels bool, descriptionLabelsRegex string) *DefaultLabelStrategy {
 	return &DefaultLabelStrategy{
 		descriptionLabels:      descriptionLabels,
 		descriptionLabelsRegex: regexp.MustCompile(descriptionLabelsRegex),
+		reservedLabels:         exporterLabels,
+		skippedKeys:            make(map[string]bool),
+	}
+}
+
+// WithDescriptionLabelKeys exports the given description labels for every protocol.
+// Missing labels are exported with empty values, other labels in the description are ignored
+func (d *DefaultLabelStrategy) WithDescriptionLabelKeys(keys []string) *DefaultLabelStrategy {
+	d.consistentLabels = true
+	d.fixedKeys = true
+	d.descriptionLabelKeys = keys
+	return d
+}
+
+// WithConsistentDescriptionLabels exports the description labels of all protocols of a scrape
+// for every protocol. Missing labels are exported with empty values
+func (d *DefaultLabelStrategy) WithConsistentDescriptionLabels() *DefaultLabelStrategy {
+	d.consistentLabels = true
+	return d
+}
+
+// WithReservedLabels ignores description labels with the given names, in addition to the labels
+// added by the exporter itself (e.g. labels of name regexes or peer metadata)
+func (d *DefaultLabelStrategy) WithReservedLabels(labels []string) *DefaultLabelStrategy {
+	d.reservedLabels = append(slices.Clone(exporterLabels), labels...)
+	return d
+}
+
+// Prepare collects the description label keys of all protocols of a scrape.
+// Keys clashing with reserved labels are skipped
+func (d *DefaultLabelStrategy) Prepare(protocols []*protocol.Protocol) error {
+	if !d.descriptionLabels || !d.consistentLabels || d.fixedKeys {
+		return nil
 	}
+
+	keys := []string{}
+	found := make(map[string]bool)
+	for _, p := range protocols {
+		for _, k := range labelKeysFromDescription(p.Description, d) {
+			if found[k] {
+				continue
+			}
+			found[k] = true
+
+			if slices.Contains(d.reservedLabels, k) {
+				d.logSkippedKey(k, p.Name)
+				continue
+			}
+
+			keys = append(keys, k)
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.descriptionLabelKeys = keys
+
+	return nil
 }
 
 // LabelNames returns the list of label names
 func (d *DefaultLabelStrategy) LabelNames(p *protocol.Protocol) []string {
 	res := []string{"name", "proto", "ip_version", "import_filter", "export_filter"}
-	if d.descriptionLabels && p.Description != "" {
+	if !d.descriptionLabels {
+		return res
+	}
+
+	if d.consistentLabels {
+		return append(res, d.keys()...)
+	}
+
+	if p.Description != "" {
 		res = append(res, labelKeysFromDescription(p.Description, d)...)
 	}
 
@@ -33,13 +110,51 @@ func (d *DefaultLabelStrategy) LabelNames(p *protocol.Protocol) []string {
 // LabelValues returns the values for a protocol
 func (d *DefaultLabelStrategy) LabelValues(p *protocol.Protocol) []string {
 	res := []string{p.Name, protoString(p), p.IPVersion, p.ImportFilter, p.ExportFilter}
-	if d.descriptionLabels && p.Description != "" {
+	if !d.descriptionLabels {
+		return res
+	}
+
+	if d.consistentLabels {
+		values := make(map[string]string)
+		keys := labelKeysFromDescription(p.Description, d)
+		for i, v := range labelValuesFromDescription(p.Description, d) {
+			values[keys[i]] = v
+		}
+
+		for _, k := range d.keys() {
+			res = append(res, values[k])
+		}
+
+		return res
+	}
+
+	if p.Description != "" {
 		res = append(res, labelValuesFromDescription(p.Description, d)...)
 	}
 
 	return res
 }
 
+// logSkippedKey logs a skipped key once to not flood the log on every scrape
+func (d *DefaultLabelStrategy) logSkippedKey(key, protocolName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.skippedKeys[key] {
+		return
+	}
+	d.skippedKeys[key] = true
+
+	log.Warnf("Ignoring description label %q of protocol %s: label is already exported", key, protocolName)
+}
+
+func (d *DefaultLabelStrategy) keys() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.descriptionLabelKeys
+}
+
 func labelKeysFromDescription(desc string, d *DefaultLabelStrategy) []string {
 	res := []string{}
 
@@ -36,3 +36,45 @@ func TestLabelValues(t *testing.T) {
 	expected := []string{"test", "BGP", "6", "in", "out", "bar"}
 	assert.Equal(t, expected, values)
 }
+
+func TestConsistentDescriptionLabels(t *testing.T) {
+	p1 := &protocol.Protocol{Name: "p1", Description: "peer=transit site=fra", Proto: protocol.BGP, IPVersion: "4"}
+	p2 := &protocol.Protocol{Name: "p2", Description: "site=ams rack=r1", Proto: protocol.BGP, IPVersion: "4"}
+	p3 := &protocol.Protocol{Name: "p3", Proto: protocol.BGP, IPVersion: "4"}
+
+	s := NewDefaultLabelStrategy(true, `(\w+)=(\w+)`).WithConsistentDescriptionLabels()
+	s.Prepare([]*protocol.Protocol{p1, p2, p3})
+
+	expected := []string{"name", "proto", "ip_version", "import_filter", "export_filter", "peer", "site", "rack"}
+	for _, p := range []*protocol.Protocol{p1, p2, p3} {
+		assert.Equal(t, expected, s.LabelNames(p), p.Name)
+	}
+
+	assert.Equal(t, []string{"p1", "BGP", "4", "", "", "transit", "fra", ""}, s.LabelValues(p1))
+	assert.Equal(t, []string{"p2", "BGP", "4", "", "", "", "ams", "r1"}, s.LabelValues(p2))
+	assert.Equal(t, []string{"p3", "BGP", "4", "", "", "", "", ""}, s.LabelValues(p3))
+}
+
+func TestDescriptionLabelKeys(t *testing.T) {
+	p := &protocol.Protocol{Name: "p1", Description: "peer=transit site=fra", Proto: protocol.BGP, IPVersion: "4"}
+
+	s := NewDefaultLabelStrategy(true, `(\w+)=(\w+)`).WithDescriptionLabelKeys([]string{"site", "customer"})
+	s.Prepare([]*protocol.Protocol{p})
+
+	assert.Equal(t, []string{"name", "proto", "ip_version", "import_filter", "export_filter", "site", "customer"}, s.LabelNames(p))
+	assert.Equal(t, []string{"p1", "BGP", "4", "", "", "fra", ""}, s.LabelValues(p))
+}
+
+func TestConsistentDescriptionLabelsSkipReserved(t *testing.T) {
+	p1 := &protocol.Protocol{Name: "p1", Description: "name=foo peer=transit state=x", Proto: protocol.BGP, IPVersion: "4"}
+	p2 := &protocol.Protocol{Name: "p2", Description: "site=ams provider=acme", Proto: protocol.BGP, IPVersion: "4"}
+
+	s := NewDefaultLabelStrategy(true, `(\w+)=(\w+)`).WithConsistentDescriptionLabels().WithReservedLabels([]string{"site", "provider"})
+	s.Prepare([]*protocol.Protocol{p1, p2})
+
+	expected := []string{"name", "proto", "ip_version", "import_filter", "export_filter", "peer"}
+	assert.Equal(t, expected, s.LabelNames(p1))
+	assert.Equal(t, expected, s.LabelNames(p2))
+	assert.Equal(t, []string{"p1", "BGP", "4", "", "", "transit"}, s.LabelValues(p1))
+	assert.Equal(t, []string{"p2", "BGP", "4", "", "", ""}, s.LabelValues(p2))
+}
@@ -24,7 +24,7 @@ func NewGenericProtocolMetricExporter(prefix string, newNaming bool, labelStrate
 func (m *GenericProtocolMetricExporter) Describe(ch chan<- *prometheus.Desc) {
 }
 
-func (m *GenericProtocolMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newNaming bool) {
+func (m *GenericProtocolMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newNaming bool) error {
 	labels := m.labelStrategy.LabelNames(p)
 
 	var importCountDesc *prometheus.Desc
@@ -95,4 +95,7 @@ func (m *GenericProtocolMetricExporter) Export(p *protocol.Protocol, ch chan<- p
 	ch <- prometheus.MustNewConstMetric(withdrawsExportFilterCountDesc, prometheus.GaugeValue, float64(p.ExportWithdraws.Filtered), l...)
 	ch <- prometheus.MustNewConstMetric(withdrawsExportAcceptCountDesc, prometheus.GaugeValue, float64(p.ExportWithdraws.Accepted), l...)
 	ch <- prometheus.MustNewConstMetric(withdrawsExportIgnoreCountDesc, prometheus.GaugeValue, float64(p.ExportWithdraws.Ignored), l...)
+	exportRouteLimits(m.prefix, p, labels, l, ch)
+
+	return nil
 }
@@ -0,0 +1,20 @@
+package metrics
+
+import "github.com/czerwonk/bird_exporter/protocol"
+
+// IdentityLabelStrategy only adds the labels identifying a protocol. It is used in combination
+// with ProtocolInfoExporter exporting all other labels
+type IdentityLabelStrategy struct {
+}
+
+func NewIdentityLabelStrategy() *IdentityLabelStrategy {
+	return &IdentityLabelStrategy{}
+}
+
+func (*IdentityLabelStrategy) LabelNames(p *protocol.Protocol) []string {
+	return []string{"name", "ip_version"}
+}
+
+func (*IdentityLabelStrategy) LabelValues(p *protocol.Protocol) []string {
+	return []string{p.Name, p.IPVersion}
+}
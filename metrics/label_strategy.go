@@ -10,3 +10,11 @@ type LabelStrategy interface {
 	// Label values is the list of values for the labels specified in `LabelNames()`
 	LabelValues(p *protocol.Protocol) []string
 }
+
+// ScrapeLabelStrategy is a LabelStrategy depending on all protocols of a scrape
+type ScrapeLabelStrategy interface {
+	LabelStrategy
+
+	// Prepare is called with all protocols of a scrape before metrics are exported
+	Prepare(protocols []*protocol.Protocol) error
+}
@@ -22,10 +22,10 @@ func (e *LegacyMetricExporter) Describe(ch chan<- *prometheus.Desc) {
 	e.ipv6Exporter.Describe(ch)
 }
 
-func (e *LegacyMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) {
+func (e *LegacyMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
 	if p.IPVersion == "4" {
-		e.ipv4Exporter.Export(p, ch, false)
-	} else {
-		e.ipv6Exporter.Export(p, ch, false)
+		return e.ipv4Exporter.Export(p, ch, false)
 	}
+
+	return e.ipv6Exporter.Export(p, ch, false)
 }
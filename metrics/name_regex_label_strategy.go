@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"regexp"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+)
+
+// NameRegexLabelStrategy adds labels extracted from protocol names by regular expressions with
+// named capture groups to the labels of another strategy. The first matching expression is used,
+// labels of all expressions are exported for every protocol (empty if not matched)
+type NameRegexLabelStrategy struct {
+	base    LabelStrategy
+	regexes []*regexp.Regexp
+	labels  []string
+}
+
+// NewNameRegexLabelStrategy creates a new instance of NameRegexLabelStrategy
+func NewNameRegexLabelStrategy(base LabelStrategy, regexes []string) *NameRegexLabelStrategy {
+	s := &NameRegexLabelStrategy{base: base}
+
+	found := make(map[string]bool)
+	for _, r := range regexes {
+		re := regexp.MustCompile(r)
+		s.regexes = append(s.regexes, re)
+
+		for _, name := range re.SubexpNames() {
+			if name != "" && !found[name] {
+				found[name] = true
+				s.labels = append(s.labels, name)
+			}
+		}
+	}
+
+	return s
+}
+
+// Prepare passes the protocols of a scrape to the base strategy
+func (s *NameRegexLabelStrategy) Prepare(protocols []*protocol.Protocol) error {
+	if b, ok := s.base.(ScrapeLabelStrategy); ok {
+		return b.Prepare(protocols)
+	}
+
+	return nil
+}
+
+// LabelNames returns the list of label names
+func (s *NameRegexLabelStrategy) LabelNames(p *protocol.Protocol) []string {
+	names := s.base.LabelNames(p)
+
+	res := make([]string, 0, len(names)+len(s.labels))
+	res = append(res, names...)
+	return append(res, s.labels...)
+}
+
+// LabelValues returns the values for a protocol
+func (s *NameRegexLabelStrategy) LabelValues(p *protocol.Protocol) []string {
+	values := s.base.LabelValues(p)
+	matched := s.match(p.Name)
+
+	res := make([]string, 0, len(values)+len(s.labels))
+	res = append(res, values...)
+	for _, l := range s.labels {
+		res = append(res, matched[l])
+	}
+
+	return res
+}
+
+func (s *NameRegexLabelStrategy) match(name string) map[string]string {
+	for _, re := range s.regexes {
+		match := re.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		res := make(map[string]string)
+		for i, n := range re.SubexpNames() {
+			if n != "" {
+				res[n] = match[i]
+			}
+		}
+
+		return res
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameRegexLabelStrategy(t *testing.T) {
+	s := NewNameRegexLabelStrategy(NewDefaultLabelStrategy(false, `(\w+)=(\w+)`), []string{
+		`^(?P<peer_type>transit|ix)_(?P<peer>[a-z0-9]+)$`,
+		`^(?P<peer_type>cust)_as(?P<asn>\d+)`,
+		`^(?P<peer_type>transit)_.*$`,
+	})
+
+	expectedNames := []string{"name", "proto", "ip_version", "import_filter", "export_filter", "peer_type", "peer", "asn"}
+
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"transit_arelion", []string{"transit", "arelion", ""}},
+		{"transit_lumen_backup", []string{"transit", "", ""}},
+		{"cust_as65000_1", []string{"cust", "", "65000"}},
+		{"ospf1", []string{"", "", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := &protocol.Protocol{Name: test.name, Proto: protocol.BGP, IPVersion: "4"}
+
+			assert.Equal(t, expectedNames, s.LabelNames(p))
+			assert.Equal(t, append([]string{test.name, "BGP", "4", "", ""}, test.expected...), s.LabelValues(p))
+		})
+	}
+}
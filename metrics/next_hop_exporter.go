@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ecmpPathsBuckets are the upper bounds of the histogram of the number of paths per route
+var ecmpPathsBuckets = []float64{1, 2, 3, 4, 6, 8, 12, 16, 32, 64}
+
+var (
+	nextHopRoutesDesc = prometheus.NewDesc("bird_route_next_hop_routes", "Number of primary routes of the protocol using the next hop (ECMP routes count for each of their next hops)", []string{"name", "ip_version", "table", "next_hop", "interface"}, nil)
+	ecmpPathsDesc     = prometheus.NewDesc("bird_route_ecmp_paths", "Distribution of the number of paths of the primary routes of the protocol", []string{"name", "ip_version", "table"}, nil)
+)
+
+// NextHopExporter exports the distribution of the primary routes of a protocol over next hops and the ECMP width of the routes
+type NextHopExporter struct {
+	client client.Client
+}
+
+// NewNextHopExporter creates a new instance of NextHopExporter
+func NewNextHopExporter(c client.Client) *NextHopExporter {
+	return &NextHopExporter{
+		client: c,
+	}
+}
+
+func (m *NextHopExporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nextHopRoutesDesc
+	ch <- ecmpPathsDesc
+}
+
+func (m *NextHopExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	if p.Up == 0 {
+		return nil
+	}
+
+	nextHops := make(map[protocol.NextHop]int64)
+	paths := make(map[int]uint64)
+	err := m.client.GetRoutes(p, "", func(r *protocol.Route) {
+		// routes without next hop (e.g. blackhole) are not forwarded
+		if !r.Primary || len(r.NextHops) == 0 {
+			return
+		}
+
+		for _, n := range r.NextHops {
+			nextHops[n]++
+		}
+		paths[len(r.NextHops)]++
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get routes of protocol %s: %w", p.Name, err)
+	}
+
+	for n, count := range nextHops {
+		ch <- prometheus.MustNewConstMetric(nextHopRoutesDesc, prometheus.GaugeValue, float64(count), p.Name, p.IPVersion, p.Table, n.Address, n.Interface)
+	}
+
+	var count uint64
+	var sum float64
+	buckets := make(map[float64]uint64, len(ecmpPathsBuckets))
+	for _, b := range ecmpPathsBuckets {
+		buckets[b] = 0
+	}
+
+	for width, n := range paths {
+		count += n
+		sum += float64(width) * float64(n)
+
+		for _, b := range ecmpPathsBuckets {
+			if float64(width) <= b {
+				buckets[b] += n
+			}
+		}
+	}
+
+	ch <- prometheus.MustNewConstHistogram(ecmpPathsDesc, count, sum, buckets, p.Name, p.IPVersion, p.Table)
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextHopExporter(t *testing.T) {
+	eth0 := protocol.NextHop{Address: "192.0.2.1", Interface: "eth0"}
+	eth1 := protocol.NextHop{Address: "192.0.2.2", Interface: "eth1"}
+	routes := []*protocol.Route{
+		{Network: "198.51.100.0/24", Primary: true, NextHops: []protocol.NextHop{eth0}},
+		{Network: "198.51.100.0/24", NextHops: []protocol.NextHop{eth1}},
+		{Network: "203.0.113.0/24", Primary: true, NextHops: []protocol.NextHop{eth0, eth1}},
+		{Network: "203.0.113.128/25", Primary: true, NextHops: []protocol.NextHop{{Interface: "eth2"}}},
+		{Network: "192.0.2.0/32", Primary: true},
+	}
+
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	p.Up = 1
+	p.Table = "master4"
+
+	c := &routeCountClient{routes: routes}
+	e := NewNextHopExporter(c)
+
+	nextHops := make(map[string]float64)
+	var hist *dto.Histogram
+	for _, m := range export(t, e, p) {
+		switch m.name {
+		case "bird_route_next_hop_routes":
+			nextHops[m.label("next_hop")+"%"+m.label("interface")] = m.GetGauge().GetValue()
+		case "bird_route_ecmp_paths":
+			hist = m.GetHistogram()
+		}
+	}
+
+	assert.Equal(t, []string{""}, c.conditions)
+	assert.Equal(t, map[string]float64{
+		"192.0.2.1%eth0": 2,
+		"192.0.2.2%eth1": 1,
+		"%eth2":          1,
+	}, nextHops)
+
+	require.NotNil(t, hist)
+	assert.Equal(t, uint64(3), hist.GetSampleCount())
+	assert.Equal(t, float64(4), hist.GetSampleSum())
+	assert.Len(t, hist.GetBucket(), len(ecmpPathsBuckets))
+	assert.Equal(t, uint64(2), hist.GetBucket()[0].GetCumulativeCount())
+	assert.Equal(t, uint64(3), hist.GetBucket()[1].GetCumulativeCount())
+}
+
+func TestNextHopExporterProtocolDown(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+
+	c := &routeCountClient{}
+	metrics := export(t, NewNextHopExporter(c), p)
+
+	assert.Empty(t, c.conditions)
+	assert.Empty(t, metrics)
+}
@@ -4,7 +4,6 @@ import (
 	"github.com/czerwonk/bird_exporter/client"
 	"github.com/czerwonk/bird_exporter/protocol"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 type ospfDesc struct {
@@ -55,7 +54,7 @@ func (m *ospfMetricExporter) describe(ipVersion string, ch chan<- *prometheus.De
 	ch <- d.neighborAdjacentCountDesc
 }
 
-func (m *ospfMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) {
+func (m *ospfMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
 	d := m.descriptions[p.IPVersion]
 
 	var running float64
@@ -67,8 +66,7 @@ func (m *ospfMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.M
 
 	areas, err := m.client.GetOSPFAreas(p)
 	if err != nil {
-		log.Errorln(err)
-		return
+		return err
 	}
 
 	for _, area := range areas {
@@ -77,4 +75,6 @@ func (m *ospfMetricExporter) Export(p *protocol.Protocol, ch chan<- prometheus.M
 		ch <- prometheus.MustNewConstMetric(d.neighborCountDesc, prometheus.GaugeValue, float64(area.NeighborCount), l...)
 		ch <- prometheus.MustNewConstMetric(d.neighborAdjacentCountDesc, prometheus.GaugeValue, float64(area.NeighborAdjacentCount), l...)
 	}
+
+	return nil
 }
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PeerMetadata is a file containing metadata of peers (YAML list of maps or CSV with header).
+// The file is reloaded on Refresh when it was modified
+type PeerMetadata struct {
+	path    string
+	mu      sync.RWMutex
+	modTime time.Time
+	index   map[string]map[string]map[string]string
+}
+
+// NewPeerMetadata creates a new instance of PeerMetadata. The file is loaded on the first Refresh
+func NewPeerMetadata(path string) *PeerMetadata {
+	return &PeerMetadata{path: path}
+}
+
+// Refresh reloads the file if it was modified since the last load
+func (m *PeerMetadata) Refresh() error {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	current := info.ModTime().Equal(m.modTime)
+	m.mu.RUnlock()
+
+	if current {
+		return nil
+	}
+
+	b, err := os.ReadFile(m.path)
+	if err != nil {
+		return err
+	}
+
+	rows, err := parsePeerMetadata(b, strings.ToLower(filepath.Ext(m.path)) == ".csv")
+	if err != nil {
+		return fmt.Errorf("could not parse peer metadata %s: %w", m.path, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.modTime = info.ModTime()
+	m.index = indexPeerMetadata(rows)
+
+	return nil
+}
+
+// Lookup returns the first row with the given value in column
+func (m *PeerMetadata) Lookup(column, value string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.index[column][value]
+}
+
+func parsePeerMetadata(b []byte, isCSV bool) ([]map[string]string, error) {
+	if !isCSV {
+		rows := []map[string]string{}
+		err := yaml.Unmarshal(b, &rows)
+		return rows, err
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, r := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			row[strings.TrimSpace(col)] = strings.TrimSpace(r[i])
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func indexPeerMetadata(rows []map[string]string) map[string]map[string]map[string]string {
+	index := make(map[string]map[string]map[string]string)
+
+	for _, row := range rows {
+		for col, value := range row {
+			if index[col] == nil {
+				index[col] = make(map[string]map[string]string)
+			}
+
+			if _, found := index[col][value]; !found {
+				index[col][value] = row
+			}
+		}
+	}
+
+	return index
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+)
+
+// PeerMetadataLabelStrategy adds columns of a peer metadata file to the labels of another strategy.
+// Protocols are joined with the file by name, neighbor address or neighbor AS
+type PeerMetadataLabelStrategy struct {
+	base     LabelStrategy
+	metadata *PeerMetadata
+	joinBy   string
+	columns  []string
+}
+
+// NewPeerMetadataLabelStrategy creates a new instance of PeerMetadataLabelStrategy. joinBy is
+// one of name, neighbor_address or neighbor_as and has to be a column of the file
+func NewPeerMetadataLabelStrategy(base LabelStrategy, metadata *PeerMetadata, joinBy string, columns []string) *PeerMetadataLabelStrategy {
+	return &PeerMetadataLabelStrategy{
+		base:     base,
+		metadata: metadata,
+		joinBy:   joinBy,
+		columns:  columns,
+	}
+}
+
+// Prepare reloads the metadata file if it was modified
+func (s *PeerMetadataLabelStrategy) Prepare(protocols []*protocol.Protocol) error {
+	if b, ok := s.base.(ScrapeLabelStrategy); ok {
+		err := b.Prepare(protocols)
+		if err != nil {
+			return err
+		}
+	}
+
+	// on errors the last loaded metadata is used
+	return s.metadata.Refresh()
+}
+
+// LabelNames returns the list of label names
+func (s *PeerMetadataLabelStrategy) LabelNames(p *protocol.Protocol) []string {
+	names := s.base.LabelNames(p)
+
+	res := make([]string, 0, len(names)+len(s.columns))
+	res = append(res, names...)
+	return append(res, s.columns...)
+}
+
+// LabelValues returns the values for a protocol
+func (s *PeerMetadataLabelStrategy) LabelValues(p *protocol.Protocol) []string {
+	values := s.base.LabelValues(p)
+
+	var row map[string]string
+	if key := s.joinValue(p); key != "" {
+		row = s.metadata.Lookup(s.joinBy, key)
+	}
+
+	res := make([]string, 0, len(values)+len(s.columns))
+	res = append(res, values...)
+	for _, col := range s.columns {
+		res = append(res, row[col])
+	}
+
+	return res
+}
+
+func (s *PeerMetadataLabelStrategy) joinValue(p *protocol.Protocol) string {
+	switch s.joinBy {
+	case "neighbor_address":
+		return p.NeighborAddress
+	case "neighbor_as":
+		if p.NeighborAS == 0 {
+			return ""
+		}
+
+		return strconv.FormatInt(p.NeighborAS, 10)
+	}
+
+	return p.Name
+}
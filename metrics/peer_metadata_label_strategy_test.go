@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerMetadataLabelStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		joinBy  string
+	}{
+		{
+			name:    "yaml by name",
+			file:    "peers.yml",
+			content: "- name: bgp1\n  provider: Arelion\n  type: transit\n- name: bgp2\n  provider: DE-CIX\n",
+			joinBy:  "name",
+		},
+		{
+			name:    "csv by neighbor address",
+			file:    "peers.csv",
+			content: "neighbor_address,provider,type\n192.0.2.1,Arelion,transit\n2001:db8::1, DE-CIX,\n",
+			joinBy:  "neighbor_address",
+		},
+		{
+			name:    "yaml by neighbor AS",
+			file:    "peers.yaml",
+			content: "- neighbor_as: 1299\n  provider: Arelion\n  type: transit\n- neighbor_as: 6695\n  provider: DE-CIX\n",
+			joinBy:  "neighbor_as",
+		},
+	}
+
+	bgp1 := &protocol.Protocol{Name: "bgp1", Proto: protocol.BGP, IPVersion: "4", NeighborAddress: "192.0.2.1", NeighborAS: 1299}
+	bgp2 := &protocol.Protocol{Name: "bgp2", Proto: protocol.BGP, IPVersion: "6", NeighborAddress: "2001:db8::1", NeighborAS: 6695}
+	bgp3 := &protocol.Protocol{Name: "bgp3", Proto: protocol.BGP, IPVersion: "4"}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			require.NoError(t, os.WriteFile(path, []byte(test.content), 0644))
+
+			s := NewPeerMetadataLabelStrategy(NewDefaultLabelStrategy(false, `(\w+)=(\w+)`), NewPeerMetadata(path), test.joinBy, []string{"provider", "type"})
+			require.NoError(t, s.Prepare([]*protocol.Protocol{bgp1, bgp2, bgp3}))
+
+			assert.Equal(t, []string{"name", "proto", "ip_version", "import_filter", "export_filter", "provider", "type"}, s.LabelNames(bgp1))
+			assert.Equal(t, []string{"bgp1", "BGP", "4", "", "", "Arelion", "transit"}, s.LabelValues(bgp1))
+			assert.Equal(t, []string{"bgp2", "BGP", "6", "", "", "DE-CIX", ""}, s.LabelValues(bgp2))
+			assert.Equal(t, []string{"bgp3", "BGP", "4", "", "", "", ""}, s.LabelValues(bgp3))
+		})
+	}
+}
+
+func TestPeerMetadataReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers.csv")
+	require.NoError(t, os.WriteFile(path, []byte("name,provider\nbgp1,Arelion\n"), 0644))
+
+	m := NewPeerMetadata(path)
+	require.NoError(t, m.Refresh())
+	assert.Equal(t, "Arelion", m.Lookup("name", "bgp1")["provider"])
+
+	require.NoError(t, os.WriteFile(path, []byte("name,provider\nbgp1,Lumen\n"), 0644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	require.NoError(t, m.Refresh())
+	assert.Equal(t, "Lumen", m.Lookup("name", "bgp1")["provider"])
+
+	require.NoError(t, os.WriteFile(path, []byte("name,provider\nbgp1\n"), 0644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+	assert.Error(t, m.Refresh())
+	assert.Equal(t, "Lumen", m.Lookup("name", "bgp1")["provider"], "last valid metadata is kept")
+}
@@ -1,12 +1,12 @@
 package metrics
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/czerwonk/bird_exporter/client"
 	"github.com/czerwonk/bird_exporter/protocol"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 // PrefixSizeExporter exports metrics for prefix length distribution
@@ -27,11 +27,10 @@ func (m *PrefixSizeExporter) Describe(ch chan<- *prometheus.Desc) {
 	// Descriptions are created dynamically based on the actual prefix lengths found
 }
 
-func (m *PrefixSizeExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) {
+func (m *PrefixSizeExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
 	stats, err := m.client.GetPrefixStats(p)
 	if err != nil {
-		log.WithError(err).WithField("protocol", p.Name).Error("Failed to get prefix statistics")
-		return
+		return fmt.Errorf("failed to get prefix statistics for protocol %s: %w", p.Name, err)
 	}
 
 	labelNames := []string{"name", "proto", "ip_version", "prefix_length"}
@@ -69,6 +68,47 @@ func (m *PrefixSizeExporter) Export(p *protocol.Protocol, ch chan<- prometheus.M
 			labelValues...,
 		)
 	}
+
+	if p.Proto != protocol.BGP {
+		return nil
+	}
+
+	if !stats.ASPathLengthsCollected && len(stats.PrefixLengthCounts) > 0 {
+		return fmt.Errorf("failed to get AS path lengths for protocol %s: routes could only be retrieved without attributes", p.Name)
+	}
+
+	if len(stats.ASPathLengthCounts) > 0 {
+		ch <- m.asPathLengthHistogram(p, stats)
+	}
+
+	return nil
+}
+
+// asPathLengthBuckets are the upper bounds of the AS path length histogram
+var asPathLengthBuckets = []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 15, 20, 30}
+
+func (m *PrefixSizeExporter) asPathLengthHistogram(p *protocol.Protocol, stats *protocol.PrefixStats) prometheus.Metric {
+	desc := prometheus.NewDesc(m.prefix+"_bgp_as_path_length", "Distribution of the AS path lengths of the routes received from the BGP session", []string{"name", "ip_version"}, nil)
+
+	var count uint64
+	var sum float64
+	buckets := make(map[float64]uint64, len(asPathLengthBuckets))
+	for _, b := range asPathLengthBuckets {
+		buckets[b] = 0
+	}
+
+	for length, n := range stats.ASPathLengthCounts {
+		count += uint64(n)
+		sum += float64(length) * float64(n)
+
+		for _, b := range asPathLengthBuckets {
+			if float64(length) <= b {
+				buckets[b] += uint64(n)
+			}
+		}
+	}
+
+	return prometheus.MustNewConstHistogram(desc, count, sum, buckets, p.Name, p.IPVersion)
 }
 
 func protocolTypeToString(proto protocol.Proto) string {
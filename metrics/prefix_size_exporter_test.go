@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/client"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type prefixStatsClient struct {
+	client.Client
+	stats *protocol.PrefixStats
+}
+
+func (c *prefixStatsClient) GetPrefixStats(p *protocol.Protocol) (*protocol.PrefixStats, error) {
+	return c.stats, nil
+}
+
+func TestPrefixSizeExporterASPathLength(t *testing.T) {
+	stats := protocol.NewPrefixStats("4", "bgp1")
+	stats.ASPathLengthsCollected = true
+	stats.AddRoute(24)
+	stats.AddASPathLength(2)
+	stats.AddASPathLength(3)
+	stats.AddASPathLength(3)
+	stats.AddASPathLength(25)
+
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	e := NewPrefixSizeExporter("bird", &prefixStatsClient{stats: stats})
+
+	ch := make(chan prometheus.Metric, 10)
+	require.NoError(t, e.Export(p, ch, true))
+	close(ch)
+
+	var h *dto.Histogram
+	for m := range ch {
+		if metricName(m) != "bird_bgp_as_path_length" {
+			continue
+		}
+
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+		h = pb.GetHistogram()
+	}
+
+	require.NotNil(t, h)
+	assert.Equal(t, uint64(4), h.GetSampleCount())
+	assert.Equal(t, float64(33), h.GetSampleSum())
+
+	buckets := map[float64]uint64{}
+	for _, b := range h.GetBucket() {
+		buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+	}
+	assert.Equal(t, uint64(0), buckets[1])
+	assert.Equal(t, uint64(1), buckets[2])
+	assert.Len(t, buckets, len(asPathLengthBuckets))
+	assert.Equal(t, uint64(3), buckets[3])
+	assert.Equal(t, uint64(3), buckets[20])
+	assert.Equal(t, uint64(4), buckets[30])
+}
+
+func TestPrefixSizeExporterASPathLengthMissing(t *testing.T) {
+	stats := protocol.NewPrefixStats("4", "bgp1")
+	stats.AddRoute(24)
+
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	e := NewPrefixSizeExporter("bird", &prefixStatsClient{stats: stats})
+
+	ch := make(chan prometheus.Metric, 10)
+	assert.Error(t, e.Export(p, ch, true))
+	close(ch)
+
+	names := []string{}
+	for m := range ch {
+		names = append(names, metricName(m))
+	}
+	assert.Equal(t, []string{"bird_prefix_length_count"}, names)
+}
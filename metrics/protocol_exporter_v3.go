@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ProtocolMetricExporterV3 exports protocol metrics in the v3 format: route changes are exported as
+// counters (with the start of the protocol as created timestamp) and the uptime as start timestamp
+type ProtocolMetricExporterV3 struct {
+	labelStrategy LabelStrategy
+	prefix        string
+}
+
+// NewProtocolMetricExporterV3 creates a new instance of ProtocolMetricExporterV3
+func NewProtocolMetricExporterV3(prefix string, labelStrategy LabelStrategy) *ProtocolMetricExporterV3 {
+	return &ProtocolMetricExporterV3{
+		prefix:        prefix,
+		labelStrategy: labelStrategy,
+	}
+}
+
+func (m *ProtocolMetricExporterV3) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (m *ProtocolMetricExporterV3) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	labels := m.labelStrategy.LabelNames(p)
+	l := m.labelStrategy.LabelValues(p)
+	startTime := time.Now().Add(-time.Duration(p.Uptime) * time.Second).Truncate(time.Second)
+
+	upDesc := prometheus.NewDesc(m.prefix+"_up", "Whether the protocol is up (1) or not (0), the state label contains the state reported by bird", append(labels, "state"), nil)
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, float64(p.Up), append(l, p.State)...)
+
+	startTimeDesc := prometheus.NewDesc(m.prefix+"_start_time_seconds", "Unix timestamp of the last state change of the protocol", labels, nil)
+	ch <- prometheus.MustNewConstMetric(startTimeDesc, prometheus.GaugeValue, float64(startTime.Unix()), l...)
+
+	for _, x := range []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"prefix_import_count", "Number of routes currently imported from the protocol", p.Imported},
+		{"prefix_export_count", "Number of routes currently exported to the protocol", p.Exported},
+		{"prefix_filter_count", "Number of routes currently filtered by the import filter", p.Filtered},
+		{"prefix_preferred_count", "Number of imported routes currently preferred in the routing table", p.Preferred},
+	} {
+		desc := prometheus.NewDesc(m.prefix+"_"+x.name, x.help, labels, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(x.value), l...)
+	}
+
+	for _, x := range []struct {
+		kind      string
+		direction string
+		count     protocol.RouteChangeCount
+	}{
+		{"update", "import", p.ImportUpdates},
+		{"withdraw", "import", p.ImportWithdraws},
+		{"update", "export", p.ExportUpdates},
+		{"withdraw", "export", p.ExportWithdraws},
+	} {
+		for _, c := range []struct {
+			result string
+			value  int64
+		}{
+			{"receive", x.count.Received},
+			{"reject", x.count.Rejected},
+			{"filter", x.count.Filtered},
+			{"ignore", x.count.Ignored},
+			{"accept", x.count.Accepted},
+		} {
+			name := m.prefix + "_changes_" + x.kind + "_" + x.direction + "_" + c.result + "_total"
+			desc := prometheus.NewDesc(name, routeChangeHelp(x.kind, x.direction, c.result), labels, nil)
+			ch <- prometheus.MustNewConstMetricWithCreatedTimestamp(desc, prometheus.CounterValue, float64(c.value), startTime, l...)
+		}
+	}
+
+	exportRouteLimits(m.prefix, p, labels, l, ch)
+
+	return nil
+}
+
+func routeChangeHelp(kind, direction, result string) string {
+	subject := "incoming " + kind + "s"
+	if direction == "export" {
+		subject = "outgoing " + kind + "s"
+	}
+
+	switch result {
+	case "receive":
+		return "Total number of " + subject + " processed since the start of the protocol"
+	case "reject":
+		return "Total number of " + subject + " rejected since the start of the protocol"
+	case "filter":
+		return "Total number of " + subject + " filtered since the start of the protocol"
+	case "ignore":
+		return "Total number of " + subject + " ignored since the start of the protocol"
+	}
+
+	return "Total number of " + subject + " accepted since the start of the protocol"
+}
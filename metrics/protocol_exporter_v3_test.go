@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolMetricExporterV3(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	p.Up = 1
+	p.State = "Established"
+	p.Imported = 12
+	p.ImportUpdates.Received = 100
+	p.ExportWithdraws.Accepted = 5
+
+	e := NewProtocolMetricExporterV3("bird_protocol", NewIdentityLabelStrategy())
+
+	ch := make(chan prometheus.Metric, 100)
+	require.NoError(t, e.Export(p, ch, true))
+	close(ch)
+
+	metrics := map[string]*dto.Metric{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+		metrics[metricName(m)] = pb
+	}
+
+	assert.Len(t, metrics, 26)
+	assert.Equal(t, float64(1), metrics["bird_protocol_up"].GetGauge().GetValue())
+	assert.Equal(t, float64(12), metrics["bird_protocol_prefix_import_count"].GetGauge().GetValue())
+
+	startTime := metrics["bird_protocol_start_time_seconds"].GetGauge().GetValue()
+	assert.InDelta(t, float64(time.Now().Add(-time.Hour).Unix()), startTime, 2)
+
+	c := metrics["bird_protocol_changes_update_import_receive_total"].GetCounter()
+	require.NotNil(t, c)
+	assert.Equal(t, float64(100), c.GetValue())
+	assert.Equal(t, int64(startTime), c.GetCreatedTimestamp().GetSeconds())
+
+	assert.Equal(t, float64(5), metrics["bird_protocol_changes_withdraw_export_accept_total"].GetCounter().GetValue())
+}
+
+func metricName(m prometheus.Metric) string {
+	desc := m.Desc().String()
+	start := len(`Desc{fqName: "`)
+
+	for i := start; i < len(desc); i++ {
+		if desc[i] == '"' {
+			return desc[start:i]
+		}
+	}
+
+	return desc
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ProtocolInfoExporter exports the labels of a protocol as info metric (constant value 1)
+type ProtocolInfoExporter struct {
+	labelStrategy LabelStrategy
+	prefix        string
+}
+
+// NewProtocolInfoExporter creates a new instance of ProtocolInfoExporter
+func NewProtocolInfoExporter(prefix string, labelStrategy LabelStrategy) *ProtocolInfoExporter {
+	return &ProtocolInfoExporter{
+		prefix:        prefix,
+		labelStrategy: labelStrategy,
+	}
+}
+
+func (m *ProtocolInfoExporter) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (m *ProtocolInfoExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
+	labels := append(m.labelStrategy.LabelNames(p), "description", "table")
+	values := append(m.labelStrategy.LabelValues(p), p.Description, p.Table)
+
+	desc := prometheus.NewDesc(m.prefix+"_info", "Information about the protocol", labels, nil)
+	metric, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, 1, values...)
+	if err != nil {
+		return err
+	}
+
+	ch <- metric
+	return nil
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/parser"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolInfoExporter(t *testing.T) {
+	p := &protocol.Protocol{
+		Name:         "bgp1",
+		Description:  "peer=transit",
+		IPVersion:    "4",
+		ImportFilter: "in",
+		ExportFilter: "out",
+		Table:        "master4",
+		Proto:        protocol.BGP,
+	}
+
+	e := NewProtocolInfoExporter("bird_protocol", NewDefaultLabelStrategy(true, `(\w+)=(\w+)`))
+
+	ch := make(chan prometheus.Metric, 1)
+	require.NoError(t, e.Export(p, ch, true))
+
+	m := &dto.Metric{}
+	require.NoError(t, (<-ch).Write(m))
+	assert.Equal(t, float64(1), m.GetGauge().GetValue())
+
+	labels := map[string]string{}
+	for _, l := range m.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+
+	expected := map[string]string{
+		"name":          "bgp1",
+		"proto":         "BGP",
+		"ip_version":    "4",
+		"import_filter": "in",
+		"export_filter": "out",
+		"peer":          "transit",
+		"description":   "peer=transit",
+		"table":         "master4",
+	}
+	assert.Equal(t, expected, labels)
+}
+
+func TestProtocolInfoExporterChannels(t *testing.T) {
+	// bird 2 lists the description of a protocol once before its channels
+	data := "Name       Proto      Table      State  Since         Info\n" +
+		"bgp1       BGP        ---        up     1494926415    Established\n" +
+		"  Description:    peer=transit\n" +
+		"  BGP state:          Established\n" +
+		"  Channel ipv4\n" +
+		"    Routes:         1 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		"  Channel ipv6\n" +
+		"    Routes:         5 imported, 6 filtered, 7 exported, 8 preferred\n" +
+		"\n"
+
+	protocols := parser.ParseProtocols([]byte(data), "")
+	require.Len(t, protocols, 2)
+
+	e := NewProtocolInfoExporter("bird_protocol", NewDefaultLabelStrategy(true, `(\w+)=(\w+)`))
+	for _, p := range protocols {
+		assert.Equal(t, "Established", p.State, "ipv%s", p.IPVersion)
+
+		ch := make(chan prometheus.Metric, 1)
+		require.NoError(t, e.Export(p, ch, true))
+
+		m := &dto.Metric{}
+		require.NoError(t, (<-ch).Write(m))
+		assert.Equal(t, "peer=transit", labelValue(m, "description"), "ipv%s", p.IPVersion)
+		assert.Equal(t, "transit", labelValue(m, "peer"), "ipv%s", p.IPVersion)
+	}
+}
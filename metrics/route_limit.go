@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// exportRouteLimits exports the receive, import and export limits of a channel, the share of the limit
+// currently used and whether the limit was hit
+func exportRouteLimits(prefix string, p *protocol.Protocol, labels, l []string, ch chan<- prometheus.Metric) {
+	if len(p.Limits) == 0 {
+		return
+	}
+
+	limitDesc := prometheus.NewDesc(prefix+"_route_limit", "Maximum number of routes of the channel, the action label contains the action taken when it is exceeded", append(labels, "type", "action"), nil)
+	usageDesc := prometheus.NewDesc(prefix+"_route_limit_usage_ratio", "Number of routes counted against the limit divided by the limit", append(labels, "type"), nil)
+	hitDesc := prometheus.NewDesc(prefix+"_route_limit_hit", "Whether the limit was hit (1) or not (0)", append(labels, "type"), nil)
+
+	for _, x := range p.Limits {
+		ch <- prometheus.MustNewConstMetric(limitDesc, prometheus.GaugeValue, float64(x.Limit), append(l, x.Type, x.Action)...)
+
+		if x.Limit > 0 {
+			ch <- prometheus.MustNewConstMetric(usageDesc, prometheus.GaugeValue, float64(routesCountedForLimit(p, x.Type))/float64(x.Limit), append(l, x.Type)...)
+		}
+
+		hit := 0
+		if x.Hit {
+			hit = 1
+		}
+		ch <- prometheus.MustNewConstMetric(hitDesc, prometheus.GaugeValue, float64(hit), append(l, x.Type)...)
+	}
+}
+
+// routesCountedForLimit returns the number of routes bird compares to a limit of the given type.
+// The receive limit includes routes rejected by the import filter
+func routesCountedForLimit(p *protocol.Protocol, limitType string) int64 {
+	switch limitType {
+	case "receive":
+		return p.Imported + p.Filtered
+	case "export":
+		return p.Exported
+	}
+
+	return p.Imported
+}
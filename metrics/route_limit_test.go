@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRouteLimits(t *testing.T) {
+	p := protocol.NewProtocol("bgp1", protocol.BGP, "4", 3600)
+	p.Imported = 750
+	p.Filtered = 250
+	p.Limits = []protocol.RouteLimit{
+		{Type: "receive", Limit: 2000, Action: "warn"},
+		{Type: "import", Limit: 1000, Action: "restart", Hit: true},
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	exportRouteLimits("bird_protocol", p, []string{"name"}, []string{"bgp1"}, ch)
+	close(ch)
+
+	metrics := map[string]*dto.Metric{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+		metrics[metricName(m)+"/"+labelValue(pb, "type")] = pb
+	}
+
+	assert.Len(t, metrics, 6)
+	assert.Equal(t, float64(1000), metrics["bird_protocol_route_limit/import"].GetGauge().GetValue())
+	assert.Equal(t, "restart", labelValue(metrics["bird_protocol_route_limit/import"], "action"))
+	assert.Equal(t, 0.75, metrics["bird_protocol_route_limit_usage_ratio/import"].GetGauge().GetValue())
+	assert.Equal(t, 0.5, metrics["bird_protocol_route_limit_usage_ratio/receive"].GetGauge().GetValue())
+	assert.Equal(t, float64(1), metrics["bird_protocol_route_limit_hit/import"].GetGauge().GetValue())
+	assert.Equal(t, float64(0), metrics["bird_protocol_route_limit_hit/receive"].GetGauge().GetValue())
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+
+	return ""
+}
@@ -1,12 +1,12 @@
 package metrics
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/czerwonk/bird_exporter/client"
 	"github.com/czerwonk/bird_exporter/protocol"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 // TablePrefixSizeExporter exports metrics for prefix length distribution across entire routing table
@@ -27,13 +27,12 @@ func (m *TablePrefixSizeExporter) Describe(ch chan<- *prometheus.Desc) {
 	// Descriptions are created dynamically based on the actual prefix lengths found
 }
 
-func (m *TablePrefixSizeExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) {
+func (m *TablePrefixSizeExporter) Export(p *protocol.Protocol, ch chan<- prometheus.Metric, newFormat bool) error {
 	// This exporter works per IP version, not per protocol
 	// We'll use the protocol's IP version to determine which table to query
 	stats, err := m.client.GetAllPrefixStats(p.IPVersion)
 	if err != nil {
-		log.WithError(err).WithField("ip_version", p.IPVersion).Error("Failed to get table-wide prefix statistics")
-		return
+		return fmt.Errorf("failed to get table-wide prefix statistics for IPv%s: %w", p.IPVersion, err)
 	}
 
 	labelNames := []string{"ip_version", "prefix_length", "table"}
@@ -75,4 +74,6 @@ func (m *TablePrefixSizeExporter) Export(p *protocol.Protocol, ch chan<- prometh
 			labelValues...,
 		)
 	}
+
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	capabilitiesRegex       = regexp.MustCompile(`^(\s+)(Local|Neighbor) capabilities$`)
+	addPathDirectionRegex   = regexp.MustCompile(`^\s+(RX|TX):\s*(.*)$`)
+	legacyCapabilitiesRegex = regexp.MustCompile(`^\s+Neighbor caps:\s+(.*)$`)
+	restartTimeRegex        = regexp.MustCompile(`^\s+Restart time:\s+(\d+)`)
+	restartActiveRegex      = regexp.MustCompile(`^\s+Neighbor graceful restart active`)
+
+	capabilityNames = map[string]string{
+		"Multiprotocol":               "multiprotocol",
+		"Route refresh":               "route_refresh",
+		"Extended next hop":           "extended_next_hop",
+		"Extended message":            "extended_message",
+		"Graceful restart":            "graceful_restart",
+		"4-octet AS numbers":          "as4",
+		"Enhanced refresh":            "enhanced_refresh",
+		"Long-lived graceful restart": "long_lived_graceful_restart",
+	}
+
+	// capabilities as listed by bird 1.x
+	legacyCapabilityNames = map[string]string{
+		"refresh":          "route_refresh",
+		"enhanced-refresh": "enhanced_refresh",
+		"restart-able":     "graceful_restart",
+		"restart-aware":    "graceful_restart",
+		"llgr-able":        "long_lived_graceful_restart",
+		"llgr-aware":       "long_lived_graceful_restart",
+		"AS4":              "as4",
+		"add-path-rx":      "add_path_rx",
+		"add-path-tx":      "add_path_tx",
+		"ext-messages":     "extended_message",
+	}
+)
+
+func parseLineForCapabilities(c *context) {
+	if c.current == nil {
+		return
+	}
+
+	if match := capabilitiesRegex.FindStringSubmatch(c.line); match != nil {
+		// capabilities are indented by 2 spaces relative to the header, their details by 4 spaces
+		// (raw socket replies have one additional leading space compared to birdc)
+		c.capabilitiesIndent = len(match[1])
+		if match[2] == "Local" {
+			c.capabilities = &c.current.LocalCapabilities
+		} else {
+			c.capabilities = &c.current.NeighborCapabilities
+		}
+
+		c.handled = true
+		return
+	}
+
+	if restartActiveRegex.MatchString(c.line) {
+		c.current.GracefulRestartActive = true
+		c.handled = true
+		return
+	}
+
+	if match := legacyCapabilitiesRegex.FindStringSubmatch(c.line); match != nil {
+		for _, x := range strings.Fields(match[1]) {
+			if name, found := legacyCapabilityNames[x]; found {
+				addCapability(&c.current.NeighborCapabilities, name)
+			}
+		}
+
+		c.handled = true
+		return
+	}
+
+	if c.capabilities == nil {
+		return
+	}
+
+	indent := len(c.line) - len(strings.TrimLeft(c.line, " "))
+	if indent <= c.capabilitiesIndent {
+		c.capabilities = nil
+		return
+	}
+
+	if indent == c.capabilitiesIndent+2 {
+		if name, found := capabilityNames[strings.TrimSpace(c.line)]; found {
+			addCapability(c.capabilities, name)
+		}
+
+		c.handled = true
+		return
+	}
+
+	if match := addPathDirectionRegex.FindStringSubmatch(c.line); match != nil {
+		if len(strings.TrimSpace(match[2])) > 0 {
+			addCapability(c.capabilities, "add_path_"+strings.ToLower(match[1]))
+		}
+
+		c.handled = true
+		return
+	}
+
+	if match := restartTimeRegex.FindStringSubmatch(c.line); match != nil {
+		if c.capabilities == &c.current.NeighborCapabilities {
+			c.current.GracefulRestartTime = int(parseInt(match[1]))
+		}
+
+		c.handled = true
+		return
+	}
+
+	// other details of a capability (e.g. announced address families)
+	c.handled = true
+}
+
+func addCapability(capabilities *[]string, name string) {
+	if !slices.Contains(*capabilities, name) {
+		*capabilities = append(*capabilities, name)
+	}
+}
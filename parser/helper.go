@@ -30,6 +30,7 @@ func parseInt(value string) int64 {
 	i, err := strconv.ParseInt(value, 10, 64)
 
 	if err != nil {
+		parseErrors.WithLabelValues("int").Inc()
 		log.Errorln(err)
 		return 0
 	}
@@ -41,6 +42,7 @@ func parseFloat(value string) float64 {
 	i, err := strconv.ParseFloat(value, 64)
 
 	if err != nil {
+		parseErrors.WithLabelValues("float").Inc()
 		log.Errorln(err)
 		return 0
 	}
@@ -51,6 +53,7 @@ func parseFloat(value string) float64 {
 func parseUptimeForIso(s string) int {
 	start, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
 	if err != nil {
+		parseErrors.WithLabelValues("timestamp").Inc()
 		log.Errorln(err)
 		return 0
 	}
@@ -66,6 +69,7 @@ func parseUptimeForDuration(duration []string) int {
 
 	d, err := time.ParseDuration(str)
 	if err != nil {
+		parseErrors.WithLabelValues("duration").Inc()
 		log.Errorln(err)
 		return 0
 	}
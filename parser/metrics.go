@@ -0,0 +1,13 @@
+package parser
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var parseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "bird_exporter_parse_errors_total",
+	Help: "Number of values in bird output which could not be parsed by type",
+}, []string{"type"})
+
+// Collectors returns the collectors instrumenting the parsing of bird output
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{parseErrors}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -18,6 +19,10 @@ var (
 	routeChangeRegex *regexp.Regexp
 	filterRegex      *regexp.Regexp
 	channelRegex     *regexp.Regexp
+	neighborRegex    *regexp.Regexp
+	tableRegex       *regexp.Regexp
+	limitRegex       *regexp.Regexp
+	limitActionRegex *regexp.Regexp
 )
 
 type context struct {
@@ -26,6 +31,11 @@ type context struct {
 	handled   bool
 	protocols []*protocol.Protocol
 	ipVersion string
+
+	// capabilities of the BGP session the current lines belong to
+	capabilities *[]string
+	// indentation of the header of the capabilities section
+	capabilitiesIndent int
 }
 
 func init() {
@@ -36,6 +46,10 @@ func init() {
 	routeChangeRegex = regexp.MustCompile(`(Import|Export) (updates|withdraws):\s+(\d+|---)\s+(\d+|---)\s+(\d+|---)\s+(\d+|---)\s+(\d+|---)\s*`)
 	filterRegex = regexp.MustCompile(`(Input|Output) filter:\s+(.*)`)
 	channelRegex = regexp.MustCompile(`Channel ipv(4|6)`)
+	tableRegex = regexp.MustCompile(`^\s+Table:\s+([^\s]+)`)
+	neighborRegex = regexp.MustCompile(`^\s+Neighbor (address|AS):\s+([^\s%]+)`)
+	limitRegex = regexp.MustCompile(`^\s+(Receive|Import|Export) limit:\s+(\d+)( \[HIT\])?`)
+	limitActionRegex = regexp.MustCompile(`^\s+Action:\s+(\w+)`)
 }
 
 // ParseProtocols parses bird output and returns protocol.Protocol structs
@@ -48,11 +62,16 @@ func ParseProtocols(data []byte, ipVersion string) []*protocol.Protocol {
 	var handlers = []func(*context){
 		handleEmptyLine,
 		parseLineForProtocol,
+		parseLineForCapabilities,
 		parseLineForDescription,
 		parseLineForChannel,
 		parseLineForRoutes,
 		parseLineForRouteChanges,
 		parseLineForFilterName,
+		parseLineForNeighbor,
+		parseLineForTable,
+		parseLineForLimit,
+		parseLineForLimitAction,
 	}
 
 	for scanner.Scan() {
@@ -75,6 +94,7 @@ func handleEmptyLine(c *context) {
 	}
 
 	c.current = nil
+	c.capabilities = nil
 	c.handled = true
 }
 
@@ -89,7 +109,11 @@ func parseLineForProtocol(c *context) {
 	ut := parseUptime(match[5])
 
 	c.current = protocol.NewProtocol(match[1], proto, c.ipVersion, ut)
+	c.capabilities = nil
 	c.current.Up = parseState(match[4])
+	if match[3] != "---" {
+		c.current.Table = match[3]
+	}
 	c.current.State = match[6]
 
 	c.protocols = append(c.protocols, c.current)
@@ -173,11 +197,23 @@ func parseLineForChannel(c *context) {
 		c.current.IPVersion = channel[1]
 	} else {
 		c.current = &protocol.Protocol{
-			Name:      c.current.Name,
-			Proto:     c.current.Proto,
-			Up:        c.current.Up,
-			Uptime:    c.current.Uptime,
-			IPVersion: channel[1],
+			Name:        c.current.Name,
+			Description: c.current.Description,
+			Proto:       c.current.Proto,
+			Up:          c.current.Up,
+			State:       c.current.State,
+			Uptime:      c.current.Uptime,
+			IPVersion:   channel[1],
+
+			NeighborAddress: c.current.NeighborAddress,
+			NeighborAS:      c.current.NeighborAS,
+
+			// capabilities are listed for the session before its channels
+			LocalCapabilities:    slices.Clone(c.current.LocalCapabilities),
+			NeighborCapabilities: slices.Clone(c.current.NeighborCapabilities),
+
+			GracefulRestartActive: c.current.GracefulRestartActive,
+			GracefulRestartTime:   c.current.GracefulRestartTime,
 		}
 		c.protocols = append(c.protocols, c.current)
 	}
@@ -271,3 +307,68 @@ func parseLineForFilterName(c *context) {
 
 	c.handled = true
 }
+
+func parseLineForNeighbor(c *context) {
+	if c.current == nil {
+		return
+	}
+
+	match := neighborRegex.FindStringSubmatch(c.line)
+	if match == nil {
+		return
+	}
+
+	if match[1] == "address" {
+		c.current.NeighborAddress = match[2]
+	} else {
+		c.current.NeighborAS = parseInt(match[2])
+	}
+
+	c.handled = true
+}
+
+func parseLineForTable(c *context) {
+	if c.current == nil {
+		return
+	}
+
+	match := tableRegex.FindStringSubmatch(c.line)
+	if match == nil {
+		return
+	}
+
+	c.current.Table = match[1]
+	c.handled = true
+}
+
+func parseLineForLimit(c *context) {
+	if c.current == nil {
+		return
+	}
+
+	match := limitRegex.FindStringSubmatch(c.line)
+	if match == nil {
+		return
+	}
+
+	c.current.Limits = append(c.current.Limits, protocol.RouteLimit{
+		Type:  strings.ToLower(match[1]),
+		Limit: parseInt(match[2]),
+		Hit:   len(match[3]) > 0,
+	})
+	c.handled = true
+}
+
+func parseLineForLimitAction(c *context) {
+	if c.current == nil || len(c.current.Limits) == 0 {
+		return
+	}
+
+	match := limitActionRegex.FindStringSubmatch(c.line)
+	if match == nil {
+		return
+	}
+
+	c.current.Limits[len(c.current.Limits)-1].Action = match[1]
+	c.handled = true
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -182,11 +183,13 @@ func TestWithBird2(t *testing.T) {
 	assert.Int64Equal("BGP ipv6 preferred", 4, x.Preferred, t)
 	assert.StringEqual("BGP import filter", "none", x.ImportFilter, t)
 	assert.StringEqual("BGP export filter", "all", x.ExportFilter, t)
+	assert.StringEqual("BGP table", "master", x.Table, t)
 
 	x = p[1]
 	assert.StringEqual("Direct ipv4 name", "direct1", x.Name, t)
 	assert.IntEqual("Direct ipv4 proto", int(protocol.Direct), int(x.Proto), t)
 	assert.StringEqual("Direct ipv4 ip version", "4", x.IPVersion, t)
+	assert.StringEqual("Direct ipv4 table", "master4", x.Table, t)
 	assert.Int64Equal("Direct ipv4 imported", 12, x.Imported, t)
 	assert.Int64Equal("Direct ipv4 exported", 34, x.Exported, t)
 	assert.Int64Equal("Direct ipv4 filtered", 1, x.Filtered, t)
@@ -216,6 +219,7 @@ func TestWithBird2(t *testing.T) {
 	assert.StringEqual("Direct ipv6 name", "direct1", x.Name, t)
 	assert.IntEqual("Direct ipv6 proto", int(protocol.Direct), int(x.Proto), t)
 	assert.StringEqual("Direct ipv6 ip version", "6", x.IPVersion, t)
+	assert.StringEqual("Direct ipv6 table", "master6", x.Table, t)
 	assert.Int64Equal("Direct ipv6 imported", 3, x.Imported, t)
 	assert.Int64Equal("Direct ipv6 exported", 5, x.Exported, t)
 	assert.Int64Equal("Direct ipv6 filtered", 7, x.Filtered, t)
@@ -293,3 +297,238 @@ func TestRPKIUp(t *testing.T) {
 	assert.StringEqual("state", "Established", x.State, t)
 	assert.IntEqual("up", 1, x.Up, t)
 }
+
+func TestBGPNeighbor(t *testing.T) {
+	data := "Name       Proto      Table      State  Since         Info\n" +
+		"bgp1       BGP        ---        up     1494926415    Established\n" +
+		"  BGP state:          Established\n" +
+		"    Neighbor address: fe80::1%eth0\n" +
+		"    Neighbor AS:      1299\n" +
+		"    Local AS:         65000\n" +
+		"  Channel ipv4\n" +
+		"    Routes:         1 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		"  Channel ipv6\n" +
+		"    Routes:         5 imported, 6 filtered, 7 exported, 8 preferred\n" +
+		"\n"
+
+	p := ParseProtocols([]byte(data), "")
+	assert.IntEqual("protocols", 2, len(p), t)
+
+	for _, x := range p {
+		assert.StringEqual("neighbor address ipv"+x.IPVersion, "fe80::1", x.NeighborAddress, t)
+		assert.Int64Equal("neighbor AS ipv"+x.IPVersion, 1299, x.NeighborAS, t)
+	}
+}
+
+func TestRouteLimits(t *testing.T) {
+	data := "Name       Proto      Table      State  Since         Info\n" +
+		"bgp1       BGP        ---        up     1494926415    Established\n" +
+		"  BGP state:          Established\n" +
+		"  Channel ipv4\n" +
+		"    Receive limit:  2000\n" +
+		"      Action:       warn\n" +
+		"    Import limit:   1000 [HIT]\n" +
+		"      Action:       block\n" +
+		"    Routes:         1000 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		"  Channel ipv6\n" +
+		"    Export limit:   100\n" +
+		"      Action:       disable\n" +
+		"    Routes:         5 imported, 6 filtered, 7 exported, 8 preferred\n" +
+		"\n"
+
+	p := ParseProtocols([]byte(data), "")
+	assert.IntEqual("protocols", 2, len(p), t)
+
+	l := p[0].Limits
+	assert.IntEqual("ipv4 limits", 2, len(l), t)
+	assert.StringEqual("receive type", "receive", l[0].Type, t)
+	assert.Int64Equal("receive limit", 2000, l[0].Limit, t)
+	assert.StringEqual("receive action", "warn", l[0].Action, t)
+	assert.True("receive not hit", !l[0].Hit, t)
+	assert.StringEqual("import type", "import", l[1].Type, t)
+	assert.Int64Equal("import limit", 1000, l[1].Limit, t)
+	assert.StringEqual("import action", "block", l[1].Action, t)
+	assert.True("import hit", l[1].Hit, t)
+
+	l = p[1].Limits
+	assert.IntEqual("ipv6 limits", 1, len(l), t)
+	assert.StringEqual("export type", "export", l[0].Type, t)
+	assert.Int64Equal("export limit", 100, l[0].Limit, t)
+	assert.StringEqual("export action", "disable", l[0].Action, t)
+}
+
+func TestBGPCapabilities(t *testing.T) {
+	data := "Name       Proto      Table      State  Since         Info\n" +
+		"bgp1       BGP        ---        up     1494926415    Established\n" +
+		"  BGP state:          Established\n" +
+		"    Neighbor address: 192.0.2.1\n" +
+		"    Neighbor AS:      1299\n" +
+		"    Local capabilities\n" +
+		"      Multiprotocol\n" +
+		"        AF announced: ipv4\n" +
+		"      Route refresh\n" +
+		"      Graceful restart\n" +
+		"        Restart time: 120\n" +
+		"        AF supported: ipv4\n" +
+		"      4-octet AS numbers\n" +
+		"      ADD-PATH\n" +
+		"        RX: ipv4\n" +
+		"        TX:\n" +
+		"      Enhanced refresh\n" +
+		"    Neighbor capabilities\n" +
+		"      Multiprotocol\n" +
+		"        AF announced: ipv4\n" +
+		"      Route refresh\n" +
+		"      4-octet AS numbers\n" +
+		"      ADD-PATH\n" +
+		"        RX:\n" +
+		"        TX: ipv4\n" +
+		"      Long-lived graceful restart\n" +
+		"    Session:          external AS4\n" +
+		"  Channel ipv4\n" +
+		"    Routes:         1 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		"\n"
+
+	p := ParseProtocols([]byte(data), "")
+	assert.IntEqual("protocols", 1, len(p), t)
+
+	x := p[0]
+	assert.StringEqual("local", "multiprotocol route_refresh graceful_restart as4 add_path_rx enhanced_refresh", strings.Join(x.LocalCapabilities, " "), t)
+	assert.StringEqual("neighbor", "multiprotocol route_refresh as4 add_path_tx long_lived_graceful_restart", strings.Join(x.NeighborCapabilities, " "), t)
+	assert.Int64Equal("imported", 1, x.Imported, t)
+}
+
+func TestBGPCapabilitiesChannels(t *testing.T) {
+	data := "Name       Proto      Table      State  Since         Info\n" +
+		"bgp1       BGP        ---        up     1494926415    Established\n" +
+		"  BGP state:          Established\n" +
+		"    Neighbor address: 2001:db8::1\n" +
+		"    Local capabilities\n" +
+		"      Multiprotocol\n" +
+		"        AF announced: ipv4 ipv6\n" +
+		"      4-octet AS numbers\n" +
+		"    Neighbor capabilities\n" +
+		"      Multiprotocol\n" +
+		"        AF announced: ipv4 ipv6\n" +
+		"      Route refresh\n" +
+		"    Session:          external AS4\n" +
+		"  Channel ipv4\n" +
+		"    Routes:         1 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		"  Channel ipv6\n" +
+		"    Routes:         5 imported, 6 filtered, 7 exported, 8 preferred\n" +
+		"\n"
+
+	p := ParseProtocols([]byte(data), "")
+	assert.IntEqual("protocols", 2, len(p), t)
+
+	for _, x := range p {
+		assert.StringEqual("local ipv"+x.IPVersion, "multiprotocol as4", strings.Join(x.LocalCapabilities, " "), t)
+		assert.StringEqual("neighbor ipv"+x.IPVersion, "multiprotocol route_refresh", strings.Join(x.NeighborCapabilities, " "), t)
+	}
+}
+
+func TestBGPCapabilitiesSocket(t *testing.T) {
+	// raw socket replies prefix continuation lines with an additional space
+	data := "2002-Name       Proto      Table      State  Since         Info\n" +
+		"1002-bgp1       BGP        ---        up     1494926415    Established\n" +
+		"1006-  BGP state:          Established\n" +
+		"     Neighbor address: 192.0.2.1\n" +
+		"     Neighbor AS:      1299\n" +
+		"     Local capabilities\n" +
+		"       Multiprotocol\n" +
+		"         AF announced: ipv4\n" +
+		"       Route refresh\n" +
+		"       Graceful restart\n" +
+		"         Restart time: 120\n" +
+		"       ADD-PATH\n" +
+		"         RX: ipv4\n" +
+		"         TX:\n" +
+		"     Neighbor capabilities\n" +
+		"       Multiprotocol\n" +
+		"         AF announced: ipv4\n" +
+		"       4-octet AS numbers\n" +
+		"       ADD-PATH\n" +
+		"         RX:\n" +
+		"         TX: ipv4\n" +
+		"     Session:          external AS4\n" +
+		"   Channel ipv4\n" +
+		"     Routes:         1 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		" \n" +
+		"0000 \n"
+
+	p := ParseProtocols([]byte(data), "")
+	assert.IntEqual("protocols", 1, len(p), t)
+
+	x := p[0]
+	assert.Int64Equal("neighbor AS", 1299, x.NeighborAS, t)
+	assert.StringEqual("local", "multiprotocol route_refresh graceful_restart add_path_rx", strings.Join(x.LocalCapabilities, " "), t)
+	assert.StringEqual("neighbor", "multiprotocol as4 add_path_tx", strings.Join(x.NeighborCapabilities, " "), t)
+	assert.Int64Equal("imported", 1, x.Imported, t)
+}
+
+func TestBGPCapabilitiesBird1(t *testing.T) {
+	data := "bgp1     BGP      master   up     1494926415  Established\n" +
+		"  Preference:     100\n" +
+		"  BGP state:          Established\n" +
+		"    Neighbor address: 192.0.2.1\n" +
+		"    Neighbor caps:    refresh enhanced-refresh restart-aware AS4 add-path-rx\n" +
+		"    Session:          external AS4\n" +
+		"\n"
+
+	p := ParseProtocols([]byte(data), "4")
+	assert.IntEqual("protocols", 1, len(p), t)
+	assert.StringEqual("neighbor", "route_refresh enhanced_refresh graceful_restart as4 add_path_rx", strings.Join(p[0].NeighborCapabilities, " "), t)
+	assert.IntEqual("local", 0, len(p[0].LocalCapabilities), t)
+}
+
+func TestBGPGracefulRestart(t *testing.T) {
+	data := "Name       Proto      Table      State  Since         Info\n" +
+		"bgp1       BGP        ---        up     1494926415    Established\n" +
+		"  BGP state:          Established\n" +
+		"    Neighbor address: 192.0.2.1\n" +
+		"    Local capabilities\n" +
+		"      Graceful restart\n" +
+		"        Restart time: 90\n" +
+		"    Neighbor capabilities\n" +
+		"      Graceful restart\n" +
+		"        Restart time: 120\n" +
+		"        AF supported: ipv4 ipv6\n" +
+		"    Neighbor graceful restart active\n" +
+		"  Channel ipv4\n" +
+		"    Routes:         1 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		"  Channel ipv6\n" +
+		"    Routes:         5 imported, 6 filtered, 7 exported, 8 preferred\n" +
+		"\n"
+
+	p := ParseProtocols([]byte(data), "")
+	assert.IntEqual("protocols", 2, len(p), t)
+
+	for _, x := range p {
+		assert.True("graceful restart active ipv"+x.IPVersion, x.GracefulRestartActive, t)
+		assert.IntEqual("restart time ipv"+x.IPVersion, 120, x.GracefulRestartTime, t)
+	}
+}
+
+func TestBGPGracefulRestartSocket(t *testing.T) {
+	data := "2002-Name       Proto      Table      State  Since         Info\n" +
+		"1002-bgp1       BGP        ---        up     1494926415    Established\n" +
+		"1006-  BGP state:          Established\n" +
+		"     Neighbor address: 192.0.2.1\n" +
+		"     Local capabilities\n" +
+		"       Graceful restart\n" +
+		"         Restart time: 90\n" +
+		"     Neighbor capabilities\n" +
+		"       Graceful restart\n" +
+		"         Restart time: 120\n" +
+		"         AF supported: ipv4\n" +
+		"     Neighbor graceful restart active\n" +
+		"   Channel ipv4\n" +
+		"     Routes:         1 imported, 2 filtered, 3 exported, 4 preferred\n" +
+		" \n" +
+		"0000 \n"
+
+	p := ParseProtocols([]byte(data), "")
+	assert.IntEqual("protocols", 1, len(p), t)
+	assert.True("graceful restart active", p[0].GracefulRestartActive, t)
+	assert.IntEqual("restart time", 120, p[0].GracefulRestartTime, t)
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"bufio"
 	"bytes"
+	"io"
 	"regexp"
 	"strconv"
 	"strings"
@@ -28,26 +29,53 @@ func ParsePrefixStats(protocolName, ipVersion string, data []byte) *protocol.Pre
 	scanner := bufio.NewScanner(reader)
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
+		addPrefixLength(stats, scanner.Text())
+	}
 
-		// Skip header and status lines
-		if strings.HasPrefix(line, "BIRD") || 
-		   strings.HasPrefix(line, "Access restricted") ||
-		   strings.Contains(line, "Table") ||
-		   strings.Contains(line, "Preference") {
-			continue
-		}
+	return stats
+}
 
-		prefixLen := extractPrefixLength(line)
-		if prefixLen > 0 {
-			stats.AddRoute(prefixLen)
+// ScanPrefixStats parses the output of "show route all" line by line and returns prefix length
+// and AS path length statistics. The output is not kept in memory
+func ScanPrefixStats(protocolName, ipVersion string, r io.Reader) (*protocol.PrefixStats, error) {
+	stats := protocol.NewPrefixStats(ipVersion, protocolName)
+	stats.ASPathLengthsCollected = true
+
+	routes := &routeScanner{fn: func(r *protocol.Route) {
+		if r.ASPath != nil {
+			stats.AddASPathLength(r.ASPathLength)
 		}
+	}}
+
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		addPrefixLength(stats, scanner.Text())
+		routes.scanLine(scanner.Text())
 	}
 
-	return stats
+	routes.flush()
+
+	return stats, scanner.Err()
+}
+
+func addPrefixLength(stats *protocol.PrefixStats, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	// Skip header and status lines
+	if strings.HasPrefix(line, "BIRD") || 
+	   strings.HasPrefix(line, "Access restricted") ||
+	   strings.Contains(line, "Table") ||
+	   strings.Contains(line, "Preference") {
+		return
+	}
+
+	prefixLen := extractPrefixLength(line)
+	if prefixLen > 0 {
+		stats.AddRoute(prefixLen)
+	}
 }
 
 // extractPrefixLength extracts the prefix length from a route line
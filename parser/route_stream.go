@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+)
+
+var (
+	replyCodeRegex   = regexp.MustCompile(`^\d{4}[- ]`)
+	routeTableRegex  = regexp.MustCompile(`^Table (\S+):$`)
+	routeHeaderRegex = regexp.MustCompile(`^(\S+)?\s+.*?\[(\S+?)[\s\]].*?\](\s+\*)?`)
+	routeAttrRegex   = regexp.MustCompile(`^\s+([\w.]+):\s*(.*)$`)
+	nextHopRegex     = regexp.MustCompile(`^\s+(?:via (\S+) on (\S+)|dev (\S+))`)
+	headerViaRegex   = regexp.MustCompile(`\svia (\S+) on (\S+)`)
+)
+
+// ScanRoutes parses the output of "show route all" line by line and calls fn for every route.
+// The route passed to fn must not be retained, as the routes are not kept in memory
+func ScanRoutes(r io.Reader, fn func(*protocol.Route)) error {
+	routes := &routeScanner{fn: fn}
+
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		routes.scanLine(scanner.Text())
+	}
+
+	routes.flush()
+
+	return scanner.Err()
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return scanner
+}
+
+// routeScanner assembles the routes of "show route all" from its lines
+type routeScanner struct {
+	fn      func(*protocol.Route)
+	current *protocol.Route
+	table   string
+	network string
+}
+
+func (s *routeScanner) flush() {
+	if s.current != nil {
+		s.fn(s.current)
+		s.current = nil
+	}
+}
+
+func (s *routeScanner) scanLine(line string) {
+	line = stripReplyCode(line)
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if match := routeTableRegex.FindStringSubmatch(line); match != nil {
+		s.flush()
+		s.table = match[1]
+		return
+	}
+
+	if match := routeAttrRegex.FindStringSubmatch(line); match != nil {
+		if s.current != nil {
+			parseRouteAttribute(s.current, match[1], match[2])
+		}
+		return
+	}
+
+	if match := nextHopRegex.FindStringSubmatch(line); match != nil {
+		if s.current != nil {
+			addNextHop(s.current, match[1], match[2]+match[3])
+		}
+		return
+	}
+
+	if match := routeHeaderRegex.FindStringSubmatch(line); match != nil {
+		s.flush()
+
+		// further routes for the same network omit the network
+		if match[1] != "" {
+			s.network = match[1]
+		}
+
+		s.current = &protocol.Route{
+			Network:  s.network,
+			Protocol: match[2],
+			Table:    s.table,
+			Primary:  match[3] != "",
+		}
+
+		if _, l, found := strings.Cut(s.network, "/"); found {
+			s.current.PrefixLen, _ = strconv.Atoi(l)
+		}
+
+		// bird 1.x lists the next hop of single path routes in the same line
+		if via := headerViaRegex.FindStringSubmatch(line[:strings.Index(line, "[")]); via != nil {
+			addNextHop(s.current, via[1], via[2])
+		}
+	}
+}
+
+// stripReplyCode removes the reply code of the bird socket protocol. Continued lines of a reply start with a space instead
+func stripReplyCode(line string) string {
+	if replyCodeRegex.MatchString(line) {
+		return line[5:]
+	}
+
+	return strings.TrimPrefix(line, " ")
+}
+
+func addNextHop(r *protocol.Route, address, iface string) {
+	if len(r.NextHops) == 0 {
+		r.NextHop = address
+	}
+
+	r.NextHops = append(r.NextHops, protocol.NextHop{Address: address, Interface: iface})
+}
+
+func parseRouteAttribute(r *protocol.Route, name, value string) {
+	switch name {
+	case "BGP.as_path":
+		r.ASPath, r.ASPathLength = parseASPath(value)
+	case "BGP.origin":
+		r.Origin = value
+	}
+}
+
+// parseASPath returns the AS numbers of the path and its length. AS sets ({...}) count as one,
+// confederation segments ((...)) are not counted
+func parseASPath(value string) ([]int64, int) {
+	path := []int64{}
+	length := 0
+	inSet := false
+	inConfed := false
+
+	for _, token := range strings.Fields(value) {
+		if strings.HasPrefix(token, "{") {
+			inSet = true
+			length++
+		}
+
+		if strings.HasPrefix(token, "(") {
+			inConfed = true
+		}
+
+		asn, err := strconv.ParseInt(strings.Trim(token, "{}()"), 10, 64)
+		if err == nil {
+			path = append(path, asn)
+
+			if !inSet && !inConfed {
+				length++
+			}
+		}
+
+		if strings.HasSuffix(token, "}") {
+			inSet = false
+		}
+
+		if strings.HasSuffix(token, ")") {
+			inConfed = false
+		}
+	}
+
+	return path, length
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/czerwonk/testutils/assert"
+)
+
+const routesAll = "1007-Table master4:\n" +
+	" 192.0.2.0/24         unicast [bgp1 2024-01-01 from 192.0.2.1] * (100) [AS65001i]\n" +
+	"\tvia 192.0.2.1 on eth0\n" +
+	"1008-\tType: BGP univ\n" +
+	"1012-\tBGP.origin: IGP\n" +
+	" \tBGP.as_path: 1299 65001\n" +
+	" \tBGP.next_hop: 192.0.2.1\n" +
+	"                      unicast [bgp2 2024-01-01] (100) [AS65001i]\n" +
+	"\tvia 192.0.2.2 on eth1\n" +
+	"1008-\tType: BGP univ\n" +
+	"1012-\tBGP.origin: IGP\n" +
+	" \tBGP.as_path: 174 (65100 65101) 3356 {65001 65002}\n" +
+	" 198.51.100.0/25      unicast [static1 2024-01-01] * (200)\n" +
+	"\tdev eth0\n" +
+	"1008-\tType: static univ\n" +
+	"0000 \n"
+
+func TestScanRoutes(t *testing.T) {
+	routes := []protocol.Route{}
+	err := ScanRoutes(strings.NewReader(routesAll), func(r *protocol.Route) {
+		routes = append(routes, *r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 3, len(routes), t)
+
+	r := routes[0]
+	assert.StringEqual("network", "192.0.2.0/24", r.Network, t)
+	assert.IntEqual("prefix length", 24, r.PrefixLen, t)
+	assert.StringEqual("protocol", "bgp1", r.Protocol, t)
+	assert.StringEqual("table", "master4", r.Table, t)
+	assert.True("primary", r.Primary, t)
+	assert.StringEqual("origin", "IGP", r.Origin, t)
+	assert.IntEqual("as path", 2, len(r.ASPath), t)
+	assert.IntEqual("as path length", 2, r.ASPathLength, t)
+	assert.IntEqual("next hops", 1, len(r.NextHops), t)
+	assert.StringEqual("next hop", "192.0.2.1", r.NextHop, t)
+	assert.StringEqual("interface", "eth0", r.NextHops[0].Interface, t)
+
+	r = routes[1]
+	assert.StringEqual("network", "192.0.2.0/24", r.Network, t)
+	assert.StringEqual("protocol", "bgp2", r.Protocol, t)
+	assert.True("not primary", !r.Primary, t)
+	assert.IntEqual("as path", 6, len(r.ASPath), t)
+	assert.IntEqual("as path length", 3, r.ASPathLength, t)
+	assert.Int64Equal("origin as", 65002, r.ASPath[5], t)
+
+	r = routes[2]
+	assert.StringEqual("network", "198.51.100.0/25", r.Network, t)
+	assert.StringEqual("protocol", "static1", r.Protocol, t)
+	assert.True("no as path", r.ASPath == nil, t)
+	assert.IntEqual("next hops", 1, len(r.NextHops), t)
+	assert.StringEqual("next hop", "", r.NextHops[0].Address, t)
+	assert.StringEqual("interface", "eth0", r.NextHops[0].Interface, t)
+}
+
+func TestScanRoutesNextHops(t *testing.T) {
+	data := "BIRD 1.6.8 ready.\n" +
+		"192.168.1.0/24      via 10.0.0.1 on eth0 [bgp1 12:34:56] * (100) [AS65001i]\n" +
+		"10.0.0.0/8          multipath [static1 12:34:56] * (200)\n" +
+		"\tvia 10.0.0.1 on eth0 weight 1\n" +
+		"\tvia 10.0.1.1 on eth1 weight 1\n" +
+		"\tvia 10.0.2.1 on eth2 weight 1\n" +
+		"2001:db8::/32       unicast [bgp2 2024-01-01 from fe80::1] * (100) [AS65001i]\n" +
+		"\tvia fe80::1 on eth0\n" +
+		"198.51.100.0/24     blackhole [static1 12:34:56] * (200)\n"
+
+	routes := []protocol.Route{}
+	err := ScanRoutes(strings.NewReader(data), func(r *protocol.Route) {
+		routes = append(routes, *r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.IntEqual("routes", 4, len(routes), t)
+	assert.IntEqual("single path", 1, len(routes[0].NextHops), t)
+	assert.StringEqual("single path next hop", "10.0.0.1", routes[0].NextHop, t)
+	assert.IntEqual("multipath", 3, len(routes[1].NextHops), t)
+	assert.StringEqual("multipath interface", "eth2", routes[1].NextHops[2].Interface, t)
+	assert.StringEqual("ipv6 next hop", "fe80::1", routes[2].NextHop, t)
+	assert.IntEqual("blackhole", 0, len(routes[3].NextHops), t)
+}
+
+func TestScanPrefixStats(t *testing.T) {
+	stats, err := ScanPrefixStats("bgp1", "4", strings.NewReader(routesAll))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True("as path lengths collected", stats.ASPathLengthsCollected, t)
+	assert.Int64Equal("prefix length 24", 1, stats.PrefixLengthCounts[24], t)
+	assert.Int64Equal("prefix length 25", 1, stats.PrefixLengthCounts[25], t)
+
+	assert.IntEqual("lengths", 2, len(stats.ASPathLengthCounts), t)
+	assert.Int64Equal("length 2", 1, stats.ASPathLengthCounts[2], t)
+	assert.Int64Equal("length 3", 1, stats.ASPathLengthCounts[3], t)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/czerwonk/bird_exporter/metrics"
+)
+
+var (
+	peerMetadataMu    sync.Mutex
+	peerMetadataFiles = make(map[string]*metrics.PeerMetadata)
+)
+
+// peerMetadataFile returns the metadata of the file. Files are shared between scrapes,
+// so they are only reloaded when modified
+func peerMetadataFile(path string) *metrics.PeerMetadata {
+	peerMetadataMu.Lock()
+	defer peerMetadataMu.Unlock()
+
+	m, found := peerMetadataFiles[path]
+	if !found {
+		m = metrics.NewPeerMetadata(path)
+		peerMetadataFiles[path] = m
+	}
+
+	return m
+}
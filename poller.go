@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	activePolling atomic.Pointer[polling]
+
+	snapshotAgeDesc = prometheus.NewDesc(
+		"bird_exporter_snapshot_age_seconds",
+		"Age of the snapshot served for a collector in background polling mode",
+		[]string{"collector"},
+		nil,
+	)
+)
+
+// snapshot is the result of the last run of a collector
+type snapshot struct {
+	timestamp time.Time
+	metrics   []prometheus.Metric
+}
+
+// poller runs the collectors of a bird instance in the background and serves
+// the last snapshot of each collector when collected
+type poller struct {
+	collector *MetricCollector
+	names     []string
+	mu        sync.RWMutex
+	snapshots map[string]*snapshot
+}
+
+func newPoller(c *MetricCollector) *poller {
+	return &poller{
+		collector: c,
+		names:     c.collectorNames(),
+		snapshots: make(map[string]*snapshot),
+	}
+}
+
+// start polls every collector on its own interval until the context is canceled
+func (p *poller) start(ctx context.Context, cfg *config.Polling) {
+	for _, name := range p.names {
+		go p.run(ctx, name, cfg.IntervalFor(name))
+	}
+}
+
+func (p *poller) run(ctx context.Context, name string, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		p.poll(name)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (p *poller) poll(name string) {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		p.collector.collect(ch, func(n string) bool { return n == name })
+		close(ch)
+	}()
+
+	metrics := make([]prometheus.Metric, 0)
+	for m := range ch {
+		metrics = append(metrics, m)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.snapshots[name] = &snapshot{timestamp: time.Now(), metrics: metrics}
+}
+
+func (p *poller) Describe(ch chan<- *prometheus.Desc) {
+	ch <- snapshotAgeDesc
+	p.collector.Describe(ch)
+}
+
+func (p *poller) Collect(ch chan<- prometheus.Metric) {
+	p.collect(ch, func(string) bool { return true })
+}
+
+func (p *poller) collectorNames() []string {
+	return p.names
+}
+
+// collect serves the snapshots of the collectors accepted by include
+func (p *poller) collect(ch chan<- prometheus.Metric, include func(name string) bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, name := range p.names {
+		if !include(name) {
+			continue
+		}
+
+		s, found := p.snapshots[name]
+		if !found {
+			continue
+		}
+
+		for _, m := range s.metrics {
+			ch <- m
+		}
+
+		ch <- prometheus.MustNewConstMetric(snapshotAgeDesc, prometheus.GaugeValue, time.Since(s.timestamp).Seconds(), name)
+	}
+}
+
+// polling is the set of pollers used for the metrics endpoint (one per bird instance)
+type polling struct {
+	cancel  context.CancelFunc
+	pollers map[string]*poller
+}
+
+// collectorFor returns the poller of the instance (empty name for the default instance)
+func (p *polling) collectorFor(instance string) scrapeCollector {
+	return p.pollers[instance]
+}
+
+func startPolling(cfg *config.Config) *polling {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &polling{
+		cancel:  cancel,
+		pollers: make(map[string]*poller),
+	}
+
+	if len(cfg.Instances) == 0 {
+		p.pollers[""] = newPoller(collectorForInstance(cfg, nil))
+	}
+
+	for _, i := range cfg.Instances {
+		p.pollers[i.Name] = newPoller(collectorForInstance(cfg, i))
+	}
+
+	for _, x := range p.pollers {
+		x.start(ctx, &cfg.Polling)
+	}
+
+	return p
+}
+
+// restartPolling stops the running pollers and starts new ones if polling is enabled
+func restartPolling(cfg *config.Config) {
+	var p *polling
+	if cfg.Polling.Enabled {
+		log.Infof("Polling collectors in background (default interval: %s)", cfg.Polling.Interval)
+		p = startPolling(cfg)
+	}
+
+	prev := activePolling.Swap(p)
+	if prev != nil {
+		prev.cancel()
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollerServesSnapshots(t *testing.T) {
+	p := &poller{
+		names: []string{"protocols", "ospf", "prefix_size"},
+		snapshots: map[string]*snapshot{
+			"protocols": {
+				timestamp: time.Now().Add(-10 * time.Second),
+				metrics:   []prometheus.Metric{prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, 1, "bgp1")},
+			},
+			"prefix_size": {
+				timestamp: time.Now().Add(-5 * time.Minute),
+				metrics:   []prometheus.Metric{prometheus.MustNewConstMetric(testDesc, prometheus.GaugeValue, 2, "bgp1")},
+			},
+		},
+	}
+
+	ch := make(chan prometheus.Metric, 10)
+	p.Collect(ch)
+	close(ch)
+
+	values := []float64{}
+	for m := range ch {
+		values = append(values, metricValue(t, m))
+	}
+
+	if assert.Len(t, values, 4) {
+		assert.Equal(t, float64(1), values[0])
+		assert.InDelta(t, 10, values[1], 1, "age of protocols snapshot")
+		assert.Equal(t, float64(2), values[2])
+		assert.InDelta(t, 300, values[3], 1, "age of prefix_size snapshot")
+	}
+}
@@ -20,6 +20,9 @@ type Protocol struct {
 	IPVersion       string
 	ImportFilter    string
 	ExportFilter    string
+	Table           string
+	NeighborAddress string
+	NeighborAS      int64
 	Proto           Proto
 	Up              int
 	State           string
@@ -32,6 +35,15 @@ type Protocol struct {
 	ImportWithdraws RouteChangeCount
 	ExportUpdates   RouteChangeCount
 	ExportWithdraws RouteChangeCount
+	Limits          []RouteLimit
+
+	// capabilities of established BGP sessions
+	LocalCapabilities    []string
+	NeighborCapabilities []string
+
+	// graceful restart of the BGP neighbor
+	GracefulRestartActive bool
+	GracefulRestartTime   int
 }
 
 type RouteChangeCount struct {
@@ -42,6 +54,14 @@ type RouteChangeCount struct {
 	Accepted int64
 }
 
+// RouteLimit represents a receive, import or export limit of a channel
+type RouteLimit struct {
+	Type   string
+	Limit  int64
+	Action string
+	Hit    bool
+}
+
 // Route represents a single route entry from BIRD
 type Route struct {
 	Network     string
@@ -50,19 +70,40 @@ type Route struct {
 	Protocol    string
 	Metric      int
 	Origin      string
+	Table       string
+	Primary     bool
+
+	// NextHops contains the paths of the route (more than one for ECMP routes)
+	NextHops []NextHop
+
+	// ASPath contains the AS numbers of the BGP.as_path attribute (nil if the route has no AS path)
+	ASPath []int64
+	// ASPathLength is the length of the AS path used in the best path selection (AS sets count as one, confederation segments are ignored)
+	ASPathLength int
+}
+
+// NextHop represents a path of a route via a gateway and/or an interface
+type NextHop struct {
+	Address   string
+	Interface string
 }
 
 // PrefixStats holds statistics about prefix counts by prefix length
 type PrefixStats struct {
 	PrefixLengthCounts map[int]int64 // map[prefix_length]count
+	ASPathLengthCounts map[int]int64 // map[as_path_length]count
 	IPVersion          string
 	Protocol           string
+
+	// ASPathLengthsCollected is set if the statistics were parsed from output containing the route attributes
+	ASPathLengthsCollected bool
 }
 
 // NewPrefixStats creates a new PrefixStats instance
 func NewPrefixStats(ipVersion, protocol string) *PrefixStats {
 	return &PrefixStats{
 		PrefixLengthCounts: make(map[int]int64),
+		ASPathLengthCounts: make(map[int]int64),
 		IPVersion:          ipVersion,
 		Protocol:           protocol,
 	}
@@ -73,6 +114,11 @@ func (ps *PrefixStats) AddRoute(prefixLen int) {
 	ps.PrefixLengthCounts[prefixLen]++
 }
 
+// AddASPathLength increments the count for the given AS path length
+func (ps *PrefixStats) AddASPathLength(length int) {
+	ps.ASPathLengthCounts[length]++
+}
+
 func NewProtocol(name string, proto Proto, ipVersion string, uptime int) *Protocol {
 	return &Protocol{Name: name, Proto: proto, IPVersion: ipVersion, Uptime: uptime}
 }
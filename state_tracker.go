@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/config"
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// startTimeTolerance is the deviation of the start time of a protocol which is not considered
+// as state change (the uptime reported by bird has a resolution of seconds)
+const startTimeTolerance = 2
+
+var (
+	activeStateStore atomic.Pointer[stateStore]
+
+	stateChangesDesc = prometheus.NewDesc(
+		"bird_protocol_state_changes_total",
+		"Number of state changes (e.g. session resets) of the protocol observed by the exporter",
+		[]string{"name", "ip_version"},
+		nil,
+	)
+	lastStateChangeDesc = prometheus.NewDesc(
+		"bird_protocol_last_state_change_timestamp_seconds",
+		"Unix timestamp of the last state change of the protocol observed by the exporter",
+		[]string{"name", "ip_version"},
+		nil,
+	)
+)
+
+// protocolState is the state of a protocol observed on the last scrape. The info column of bird
+// (e.g. Active, Connect or error messages of BGP sessions) is not part of the state, since it
+// changes frequently while a protocol is down
+type protocolState struct {
+	Name       string  `json:"name"`
+	IPVersion  string  `json:"ip_version"`
+	Up         int     `json:"up"`
+	StartTime  int64   `json:"start_time"`
+	Changes    float64 `json:"changes"`
+	LastChange int64   `json:"last_change"`
+}
+
+// stateStore contains the protocol states of all bird instances. If a path is set the states
+// are persisted, so state changes are tracked across restarts of the exporter
+type stateStore struct {
+	path      string
+	mu        sync.Mutex
+	instances map[string]map[string]*protocolState
+}
+
+func newStateStore(path string) (*stateStore, error) {
+	s := &stateStore{
+		path:      path,
+		instances: make(map[string]map[string]*protocolState),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &s.instances)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// observe compares the protocols with the states of the last scrape of the instance
+func (s *stateStore) observe(instance string, protocols []*protocol.Protocol, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.instances[instance]
+	states := make(map[string]*protocolState, len(protocols))
+	changed := false
+
+	for _, p := range protocols {
+		// protocols of bird and bird6 (or channels of bird 2) may have the same name
+		key := p.Name + "/" + p.IPVersion
+		startTime := now.Unix() - int64(p.Uptime)
+
+		x, found := prev[key]
+		if !found {
+			states[key] = &protocolState{Name: p.Name, IPVersion: p.IPVersion, Up: p.Up, StartTime: startTime, LastChange: startTime}
+			changed = true
+			continue
+		}
+
+		if p.Up != x.Up || startTime > x.StartTime+startTimeTolerance {
+			x.Changes++
+			x.LastChange = startTime
+			x.Up = p.Up
+			x.StartTime = startTime
+			changed = true
+		}
+
+		states[key] = x
+	}
+
+	s.instances[instance] = states
+
+	if changed || len(states) != len(prev) {
+		s.persist()
+	}
+}
+
+func (s *stateStore) persist() {
+	if s.path == "" {
+		return
+	}
+
+	b, err := json.Marshal(s.instances)
+	if err != nil {
+		log.Errorf("could not persist protocol states: %v", err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".bird_exporter_state_*")
+	if err != nil {
+		log.Errorf("could not persist protocol states: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+
+	if err != nil {
+		log.Errorf("could not persist protocol states: %v", err)
+	}
+}
+
+func (s *stateStore) export(instance string, ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, x := range s.instances[instance] {
+		ch <- prometheus.MustNewConstMetric(stateChangesDesc, prometheus.CounterValue, x.Changes, x.Name, x.IPVersion)
+		ch <- prometheus.MustNewConstMetric(lastStateChangeDesc, prometheus.GaugeValue, float64(x.LastChange), x.Name, x.IPVersion)
+	}
+}
+
+// stateTracker tracks the state changes of the protocols of a single bird instance
+type stateTracker struct {
+	store    *stateStore
+	instance string
+}
+
+func (t *stateTracker) track(protocols []*protocol.Protocol, ch chan<- prometheus.Metric) {
+	t.store.observe(t.instance, protocols, time.Now())
+	t.store.export(t.instance, ch)
+}
+
+// stateTrackerFor returns the tracker of the instance (nil if state tracking is disabled)
+func stateTrackerFor(instance string) *stateTracker {
+	s := activeStateStore.Load()
+	if s == nil {
+		return nil
+	}
+
+	return &stateTracker{store: s, instance: instance}
+}
+
+// updateStateStore creates the state store when state tracking was enabled or the state file changed
+func updateStateStore(cfg *config.StateTracking) error {
+	if !cfg.Enabled {
+		activeStateStore.Store(nil)
+		return nil
+	}
+
+	current := activeStateStore.Load()
+	if current != nil && current.path == cfg.File {
+		return nil
+	}
+
+	s, err := newStateStore(cfg.File)
+	if err != nil {
+		return err
+	}
+
+	activeStateStore.Store(s)
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/czerwonk/bird_exporter/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bgpProtocol(name, state string, up, uptime int) *protocol.Protocol {
+	p := protocol.NewProtocol(name, protocol.BGP, "4", uptime)
+	p.State = state
+	p.Up = up
+
+	return p
+}
+
+func TestStateStoreObserve(t *testing.T) {
+	s, err := newStateStore(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	s.observe("r1", []*protocol.Protocol{
+		bgpProtocol("bgp1", "Established", 1, 3600),
+		bgpProtocol("bgp2", "Established", 1, 60),
+	}, now)
+
+	// uptime increased as expected (with jitter of a second)
+	now = now.Add(time.Minute)
+	s.observe("r1", []*protocol.Protocol{
+		bgpProtocol("bgp1", "Established", 1, 3661),
+		bgpProtocol("bgp2", "Established", 1, 120),
+	}, now)
+
+	assert.Equal(t, float64(0), s.instances["r1"]["bgp1/4"].Changes)
+	assert.Equal(t, float64(0), s.instances["r1"]["bgp2/4"].Changes)
+
+	// bgp1 was reset between scrapes, bgp2 went down
+	now = now.Add(time.Minute)
+	s.observe("r1", []*protocol.Protocol{
+		bgpProtocol("bgp1", "Established", 1, 10),
+		bgpProtocol("bgp2", "Active", 0, 5),
+	}, now)
+
+	bgp1 := s.instances["r1"]["bgp1/4"]
+	assert.Equal(t, float64(1), bgp1.Changes)
+	assert.Equal(t, now.Unix()-10, bgp1.LastChange)
+	assert.Equal(t, float64(1), s.instances["r1"]["bgp2/4"].Changes)
+
+	// bgp2 is still down, bird switches between Active and Connect
+	for _, state := range []string{"Connect", "Active", "Idle", "Error: Hold timer expired"} {
+		s.observe("r1", []*protocol.Protocol{
+			bgpProtocol("bgp1", "Established", 1, 10),
+			bgpProtocol("bgp2", state, 0, 5),
+		}, now)
+	}
+	assert.Equal(t, float64(1), s.instances["r1"]["bgp2/4"].Changes)
+
+	// states are persisted
+	loaded, err := newStateStore(s.path)
+	require.NoError(t, err)
+	assert.Equal(t, s.instances, loaded.instances)
+}
+
+func TestStateStoreRemovesProtocols(t *testing.T) {
+	s, err := newStateStore("")
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	s.observe("", []*protocol.Protocol{bgpProtocol("bgp1", "Established", 1, 60), bgpProtocol("bgp2", "Established", 1, 60)}, now)
+	s.observe("", []*protocol.Protocol{bgpProtocol("bgp1", "Established", 1, 60)}, now)
+
+	assert.Len(t, s.instances[""], 1)
+	assert.Contains(t, s.instances[""], "bgp1/4")
+}
+
+func TestStateStoreSameNameBothDaemons(t *testing.T) {
+	s, err := newStateStore("")
+	require.NoError(t, err)
+
+	kernel := func(ipVersion string, uptime int) *protocol.Protocol {
+		return protocol.NewProtocol("kernel1", protocol.Kernel, ipVersion, uptime)
+	}
+
+	now := time.Unix(1700000000, 0)
+	s.observe("", []*protocol.Protocol{kernel("4", 3600), kernel("6", 3600)}, now)
+
+	// only the kernel protocol of bird6 was restarted
+	now = now.Add(time.Minute)
+	s.observe("", []*protocol.Protocol{kernel("4", 3660), kernel("6", 10)}, now)
+
+	assert.Len(t, s.instances[""], 2)
+	assert.Equal(t, float64(0), s.instances[""]["kernel1/4"].Changes)
+	assert.Equal(t, float64(1), s.instances[""]["kernel1/6"].Changes)
+
+	ch := make(chan prometheus.Metric, 10)
+	s.export("", ch)
+	close(ch)
+	assert.Len(t, ch, 4)
+}
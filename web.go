@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stringsFlag is a flag which can be specified multiple times
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// legacyTLSWebConfig writes a web config file equivalent to the deprecated -tls.* flags
+func legacyTLSWebConfig(certFile, keyFile string) (string, error) {
+	certFile, err := filepath.Abs(certFile)
+	if err != nil {
+		return "", err
+	}
+
+	keyFile, err = filepath.Abs(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := yaml.Marshal(map[string]map[string]string{
+		"tls_server_config": {
+			"cert_file": certFile,
+			"key_file":  keyFile,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "bird_exporter_web_config_*.yml")
+	if err != nil {
+		return "", fmt.Errorf("could not create web config for TLS: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(b)
+	if err != nil {
+		return "", fmt.Errorf("could not create web config for TLS: %w", err)
+	}
+
+	return f.Name(), nil
+}